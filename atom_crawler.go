@@ -10,7 +10,8 @@ import (
 )
 
 func (c *Crawler) OnXML_AtomFeed(headers *http.Header, r *colly.Request, channel *xmlquery.Node) {
-	feed_url := r.URL.String()
+	hub, self := c.findHubAndSelf(r, channel)
+	feed_url := c.canonicalFeedUrl(r, self)
 
 	links := collectLinkHrefs(r, "link[@rel='alternate' and @type='text/html']", channel)
 	if len(links) == 0 {
@@ -22,32 +23,54 @@ func (c *Crawler) OnXML_AtomFeed(headers *http.Header, r *colly.Request, channel
 	title := xmlText(channel, "title")
 	description := xmlText(channel, "subtitle")
 	date := fmtDate(xmlText(channel, "updated"))
-	categories := xmlPathAttrMultiple(channel, "category", "term")
+	categories := c.Config.Categories.Normalize(xmlPathAttrMultiple(channel, "category", "term"))
 
 	// Find a top level language
 	language := strings.TrimSpace(channel.SelectAttr("xml:lang"))
 
+	link := ""
+	if len(links) > 0 {
+		link = links[0]
+	}
+	override := c.Config.OverrideFor(feed_url, r.URL.String(), link)
+	if override.Language != nil {
+		language = *override.Language
+	}
+
+	if isBackfillPage(r) {
+		// An older page of a feed we've already processed
+		c.CollectAtomEntries(r, channel, link, language, override)
+		c.Backfill(r, channel, true)
+		return
+	}
+
 	feed := NewFeedFrontmatter(feed_url)
 	feed.WithDate(date)
 	feed.WithTitle(title)
 	feed.WithFeedType("atom")
 	feed.WithDescription(description)
 	feed.WithCategories(categories)
+	feed.WithItemLinks(collectLinkHrefs(r, "entry/link", channel))
 	feed.WithLanguage(language)
+	feed.WithHub(hub)
+	feed.WithSelfLink(self)
 	setNoArchive(feed, headers)
+	feed.WithOverride(override)
 
-	if blocked, blockWord := hasBlockWords(title, c.Config); blocked {
-		log.Printf("Word in title is blocked: %s", blockWord)
+	blockable := &Blockable{
+		Title:       title,
+		Description: description,
+		Author:      xmlText(channel, "author/name"),
+		Categories:  categories,
+	}
+	if c.isBlocked("feed", feed_url, blockable, override) {
 		return
 	}
-	if blocked, blockWord := hasBlockWords(description, c.Config); blocked {
-		log.Printf("Word in description is blocked: %s", blockWord)
+	if c.isBlockedCategory("feed", feed_url, title, feed.Params.Categories) {
 		return
 	}
 
-	link := ""
 	if len(links) > 0 {
-		link = links[0]
 		feed.WithLink(link)
 		if len(links) > 1 {
 			log.Printf("TODO: Add support for multiple links: %s", feed_url)
@@ -63,8 +86,10 @@ func (c *Crawler) OnXML_AtomFeed(headers *http.Header, r *colly.Request, channel
 		return
 	}
 
-	log.Println("DEPTH:", r.Depth)
-	isDirect := r.Depth < 4
+	c.TrackSelfLink(r, feed_url)
+
+	// Refined using the whole link graph after the crawl
+	isDirect := c.IsFollowed(requestedFeedUrlOf(r))
 
 	if len(link) > 0 {
 		log.Printf("Searching for blogroll in: %s", link)
@@ -74,30 +99,46 @@ func (c *Crawler) OnXML_AtomFeed(headers *http.Header, r *colly.Request, channel
 	// Atom feeds don't have a blogroll syntax yet
 	// Add here when they do
 
-	postCount, avgPostLen, avgPostPerDay := c.CollectAtomEntries(r, channel, language)
+	postCount, avgPostLen, avgPostPerDay, futurePosts := c.CollectAtomEntries(r, channel, link, language, override)
 	feed.WithPostCount(postCount)
 	feed.WithAvgPostLen(avgPostLen)
 	feed.WithAvgPostPerDay(avgPostPerDay)
+	feed.WithFuturePosts(futurePosts)
 	c.SaveFeed(feed, isDirect)
+	c.Backfill(r, channel, isDirect)
+	if isDirect {
+		c.TrackWebSub(feed, r.Depth)
+	}
 }
 
-func (c *Crawler) CollectAtomEntries(r *colly.Request, channel *xmlquery.Node, feed_language string) (int, int, float32) {
+func (c *Crawler) CollectAtomEntries(r *colly.Request, channel *xmlquery.Node, website, feed_language string, override FeedOverride) (int, int, float32, int) {
 	if r.Depth > c.Config.PostCollectionDepth {
-		return 0, 0, 0.0
+		return 0, 0, 0.0, 0
 	}
-	if c.Config.MaxPostsPerFeed < 1 {
-		return 0, 0, 0.0
+	maxPostsPerFeed := intDefault(override.MaxPostsPerFeed, c.Config.MaxPostsPerFeed)
+	if maxPostsPerFeed < 1 {
+		return 0, 0, 0.0, 0
 	}
 
 	posts := []*PostFrontmatter{}
+	futurePosts := 0
 	xmlItems := xmlquery.Find(channel, "//entry")
 	for _, entry := range xmlItems {
-		entries, ok := c.OnXML_AtomEntry(r, entry, feed_language)
+		entries, ok := c.OnXML_AtomEntry(r, entry, website, feed_language, override)
 		if ok {
 			posts = append(posts, entries...)
 		}
 	}
 
+	// Misconfigured feeds may have posts dated years ahead
+	posts = slices.DeleteFunc(posts, func(post *PostFrontmatter) bool {
+		keep, isFuture := c.checkFutureDate(r, post)
+		if isFuture {
+			futurePosts++
+		}
+		return !keep
+	})
+
 	slices.SortFunc(posts, func(a, b *PostFrontmatter) int {
 		// Reverse chronological
 		return cmpDateStr(b.Date, a.Date)
@@ -106,7 +147,7 @@ func (c *Crawler) CollectAtomEntries(r *colly.Request, channel *xmlquery.Node, f
 	postLenSum := int(0)
 	for i, post := range posts {
 		postLenSum += len(post.Params.Content)
-		if i < c.Config.MaxPostsPerFeed {
+		if i < maxPostsPerFeed && !boolDefault(override.HidePosts, false) {
 			c.SavePost(post)
 		}
 	}
@@ -130,11 +171,11 @@ func (c *Crawler) CollectAtomEntries(r *colly.Request, channel *xmlquery.Node, f
 			}
 		}
 	}
-	return numPosts, avgPostLen, avgPostPerDay
+	return numPosts, avgPostLen, avgPostPerDay, futurePosts
 }
 
-func (c *Crawler) OnXML_AtomEntry(r *colly.Request, entry *xmlquery.Node, feed_language string) ([]*PostFrontmatter, bool) {
-	feed_url := r.URL.String()
+func (c *Crawler) OnXML_AtomEntry(r *colly.Request, entry *xmlquery.Node, website, feed_language string, override FeedOverride) ([]*PostFrontmatter, bool) {
+	feed_url := feedUrlOf(r)
 
 	post_id := xmlText(entry, "id")
 	links := collectLinkHrefs(r, "link[@rel='alternate']", entry)
@@ -150,10 +191,10 @@ func (c *Crawler) OnXML_AtomEntry(r *colly.Request, entry *xmlquery.Node, feed_l
 	if dateStr == "" {
 		dateStr = xmlText(entry, "published")
 	}
-	date := fmtDate(dateStr)
 
 	content := xmlText(entry, "content")
-	categories := xmlPathAttrMultiple(entry, "category", "term")
+	categories := c.Config.Categories.Normalize(xmlPathAttrMultiple(entry, "category", "term"))
+	author := xmlText(entry, "author/name")
 
 	// Prefer languages set on the element itself
 	language := xmlAttr(entry, "xml:lang")
@@ -174,6 +215,11 @@ func (c *Crawler) OnXML_AtomEntry(r *colly.Request, entry *xmlquery.Node, feed_l
 		language = feed_language
 	}
 
+	// Unless the language was forced for this feed
+	if override.Language != nil {
+		language = *override.Language
+	}
+
 	// TODO, parse type: https://validator.w3.org/feed/docs/atom.html#text
 	//       if type=html, convert back to plain text
 	description := xmlText(entry, "summary")
@@ -181,16 +227,37 @@ func (c *Crawler) OnXML_AtomEntry(r *colly.Request, entry *xmlquery.Node, feed_l
 	if title == "" {
 		return nil, false
 	}
-	if blocked, blockWord := hasBlockWords(title, c.Config); blocked {
-		log.Printf("Word in title is blocked: %s", blockWord)
+
+	// Last, guess from the text
+	if len(language) == 0 {
+		language = detectLanguage(strings.Join([]string{title, readable(description), readable(content)}, "\n"))
+	}
+
+	// Log the entry's link like RSS does, IDs are often tag: URIs
+	entry_link := post_id
+	if len(links) > 0 {
+		entry_link = links[0]
+	}
+	blockable := &Blockable{
+		Title:       title,
+		Description: description,
+		Content:     content,
+		Author:      author,
+		Categories:  slices.Concat(categories, override.Categories),
+	}
+	if c.isBlocked("post", entry_link, blockable, override) {
+		return nil, false
+	}
+	if c.isBlockedCategory("post", entry_link, title, blockable.Categories) {
 		return nil, false
 	}
-	if blocked, blockWord := hasBlockWords(description, c.Config); blocked {
-		log.Printf("Word in description is blocked: %s", blockWord)
+	languageTag, _ := normalizeLanguageTag(language)
+	baseLanguage, _ := languageFromLanguageTag(languageTag)
+	inclusion := c.inclusionOf(entry_link, blockable, baseLanguage)
+	if inclusion == INCLUDE_NEVER {
 		return nil, false
 	}
-	if blocked, blockWord := hasBlockWords(content, c.Config); blocked {
-		log.Printf("Word in content is blocked: %s", blockWord)
+	if !c.isAllowedLanguage(entry_link, languageTag) {
 		return nil, false
 	}
 
@@ -204,15 +271,18 @@ func (c *Crawler) OnXML_AtomEntry(r *colly.Request, entry *xmlquery.Node, feed_l
 			// This isn't a web link
 			continue
 		}
+		link = c.CleanPostLink(link)
 
 		post := NewPostFrontmatter(feed_url, post_id, link)
 		post.WithTitle(title)
 		post.WithDescription(description)
-		post.WithDate(date)
 		post.WithContent(content)
 		post.WithFeedLink(feed_url)
 		post.WithCategories(categories)
+		post.WithCategories(override.Categories)
+		post.WithAuthor(author)
 		post.WithLanguage(language)
+		post.WithInclusion(inclusion)
 
 		if isBlockedPost(link, title, post.Params.Id, c.Config) {
 			continue
@@ -222,6 +292,8 @@ func (c *Crawler) OnXML_AtomEntry(r *colly.Request, entry *xmlquery.Node, feed_l
 			continue
 		}
 
+		c.TrackCitations(r, website, link, description, content)
+		c.datePost(r, post, dateStr, entry.Parent, "updated")
 		found = append(found, post)
 	}
 	return found, true
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+)
+
+const (
+	BLOCK_MATCH_WORD      = "word"
+	BLOCK_MATCH_REGEX     = "regex"
+	BLOCK_MATCH_SUBSTRING = "substring"
+)
+
+const (
+	BLOCK_FIELD_TITLE       = "title"
+	BLOCK_FIELD_DESCRIPTION = "description"
+	BLOCK_FIELD_CONTENT     = "content"
+	BLOCK_FIELD_CATEGORIES  = "categories"
+	BLOCK_FIELD_AUTHOR      = "author"
+)
+
+var BLOCK_FIELDS = []string{
+	BLOCK_FIELD_TITLE,
+	BLOCK_FIELD_DESCRIPTION,
+	BLOCK_FIELD_CONTENT,
+	BLOCK_FIELD_CATEGORIES,
+	BLOCK_FIELD_AUTHOR,
+}
+
+type BlockRule struct {
+	Pattern string `yaml:"pattern"`
+	// word (default), regex or substring
+	Match         string `yaml:"match"`
+	CaseSensitive bool   `yaml:"case_sensitive"`
+	// Defaults to all fields
+	Fields []string `yaml:"fields"`
+}
+
+type CompiledBlockRule struct {
+	Rule    BlockRule
+	Private bool
+	re      *regexp.Regexp
+}
+
+func (r BlockRule) Compile(private bool) *CompiledBlockRule {
+	expr := ""
+	switch r.Match {
+	case "", BLOCK_MATCH_WORD:
+		// Letters and numbers on either side make it part of another word
+		expr = `(?:^|[^\p{L}\p{N}_])` + regexp.QuoteMeta(r.Pattern) + `(?:$|[^\p{L}\p{N}_])`
+	case BLOCK_MATCH_SUBSTRING:
+		expr = regexp.QuoteMeta(r.Pattern)
+	case BLOCK_MATCH_REGEX:
+		expr = r.Pattern
+	default:
+		panicf("Unknown block rule match: %s", r.Match)
+	}
+	if !r.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		panicf("Invalid block rule: %s: %v", r.Pattern, err)
+	}
+	for _, field := range r.Fields {
+		if !slices.Contains(BLOCK_FIELDS, field) {
+			panicf("Unknown block rule field: %s", field)
+		}
+	}
+	return &CompiledBlockRule{
+		Rule:    r,
+		Private: private,
+		re:      re,
+	}
+}
+
+// Block words are case-sensitive substrings in the title, description or content
+func blockWordRules(words []string, private bool) []*CompiledBlockRule {
+	rules := []*CompiledBlockRule{}
+	for _, word := range words {
+		rule := BlockRule{
+			Pattern:       word,
+			Match:         BLOCK_MATCH_SUBSTRING,
+			CaseSensitive: true,
+			Fields:        []string{BLOCK_FIELD_TITLE, BLOCK_FIELD_DESCRIPTION, BLOCK_FIELD_CONTENT},
+		}
+		rules = append(rules, rule.Compile(private))
+	}
+	return rules
+}
+
+func compileBlockRules(blockRules []BlockRule, private bool) []*CompiledBlockRule {
+	rules := []*CompiledBlockRule{}
+	for _, rule := range blockRules {
+		rules = append(rules, rule.Compile(private))
+	}
+	return rules
+}
+
+// The fields of a feed or post that block rules can match
+type Blockable struct {
+	Title       string
+	Description string
+	Content     string
+	Author      string
+	Categories  []string
+}
+
+func (b *Blockable) Field(field string) []string {
+	switch field {
+	case BLOCK_FIELD_TITLE:
+		return []string{b.Title}
+	case BLOCK_FIELD_DESCRIPTION:
+		return []string{b.Description}
+	case BLOCK_FIELD_CONTENT:
+		return []string{b.Content}
+	case BLOCK_FIELD_AUTHOR:
+		return []string{b.Author}
+	case BLOCK_FIELD_CATEGORIES:
+		return b.Categories
+	}
+	return nil
+}
+
+// Returns the field the rule matched in
+func (r *CompiledBlockRule) Matches(b *Blockable) (bool, string) {
+	fields := r.Rule.Fields
+	if len(fields) == 0 {
+		fields = BLOCK_FIELDS
+	}
+	for _, field := range fields {
+		for _, text := range b.Field(field) {
+			if text != "" && r.re.MatchString(text) {
+				return true, field
+			}
+		}
+	}
+	return false, ""
+}
+
+// How the rule is shown in the moderation log
+// Private rules aren't shared with the world
+func (r *CompiledBlockRule) String() string {
+	if r.Private {
+		return "(private)"
+	}
+	match := r.Rule.Match
+	if match == "" {
+		match = BLOCK_MATCH_WORD
+	}
+	return fmt.Sprintf("%s:%s", match, r.Rule.Pattern)
+}
+
+type ModerationEntry struct {
+	Kind  string // feed or post
+	Link  string
+	Title string
+	Rule  string
+	Field string
+}
+
+// Check the global and per-feed block rules, logging any match
+func (c *Crawler) isBlocked(kind, link string, b *Blockable, override FeedOverride) bool {
+	rules := slices.Concat(c.Config.BlockRules, override.blockRules)
+	for _, rule := range rules {
+		matched, field := rule.Matches(b)
+		if !matched {
+			continue
+		}
+		log.Printf("Blocked %s by rule %s in %s: %s", kind, rule, field, link)
+		c.TrackModeration(ModerationEntry{
+			Kind:  kind,
+			Link:  link,
+			Title: b.Title,
+			Rule:  rule.String(),
+			Field: field,
+		})
+		return true
+	}
+	return false
+}
+
+func (c *Crawler) TrackModeration(entry ModerationEntry) {
+	c.Results.lock.Lock()
+	defer c.Results.lock.Unlock()
+	c.Results.Moderation = append(c.Results.Moderation, entry)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"strings"
+)
+
+// Disjoint sets of site keys
+type unionFind map[string]string
+
+func (u unionFind) find(key string) string {
+	parent, ok := u[key]
+	if !ok || parent == key {
+		return key
+	}
+	root := u.find(parent)
+	u[key] = root
+	return root
+}
+
+func (u unionFind) union(a, b string) {
+	rootA := u.find(a)
+	rootB := u.find(b)
+	if rootA != rootB {
+		u[rootA] = rootB
+	}
+}
+
+// Which of two URLs for the same page to prefer
+func cmpCanonicalPreference(a, b string, targets map[string]bool) int {
+	// Prefer URLs that a page or feed declared as canonical
+	if targets[a] != targets[b] {
+		if targets[a] {
+			return -1
+		}
+		return 1
+	}
+	// Then HTTPS
+	aHttps := strings.HasPrefix(a, "https://")
+	bHttps := strings.HasPrefix(b, "https://")
+	if aHttps != bHttps {
+		if aHttps {
+			return -1
+		}
+		return 1
+	}
+	// Then the shortest, to drop www. and trailing slashes
+	if len(a) != len(b) {
+		return cmp.Compare(len(a), len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+func isCanonicalLinkType(linkType string) bool {
+	return linkType == LINK_TYPE_LINK_REL_CANONICAL || linkType == LINK_TYPE_LINK_REL_SELF
+}
+
+// Merge feeds and websites that are the same site under different URLs
+//
+// URLs are considered the same when they only differ by scheme, www.
+// prefix or trailing slash, or when one declared the other canonical.
+// Feeds with the same title and website that share posts are mirrors.
+func (c *Crawler) MergeDuplicates() {
+	results := c.Results
+	sets := unionFind{}
+	targets := map[string]bool{}
+	urls := map[string]bool{}
+
+	for _, link := range results.Links {
+		urls[link.Params.SourceURL] = true
+		urls[link.Params.DestinationURL] = true
+		if isCanonicalLinkType(link.Params.LinkType) {
+			sets.union(siteKey(link.Params.SourceURL), siteKey(link.Params.DestinationURL))
+			targets[link.Params.DestinationURL] = true
+		}
+	}
+	for feedLink, found := range results.Feeds {
+		urls[feedLink] = true
+		if found.Feed.Params.Link != "" {
+			urls[found.Feed.Params.Link] = true
+		}
+	}
+
+	// Mirrors, feeds with the same title for the same website that share posts
+	mirrors := map[string][]*FeedFrontmatter{}
+	for _, feedLink := range sortedKeys(results.Feeds) {
+		feed := results.Feeds[feedLink].Feed
+		if feed.Params.Link == "" || feed.Title == "" {
+			continue
+		}
+		key := sets.find(siteKey(feed.Params.Link)) + "\n" + strings.ToLower(feed.Title)
+		for _, other := range mirrors[key] {
+			if sharesAny(feed.itemLinks, other.itemLinks) {
+				sets.union(siteKey(other.Params.FeedLink), siteKey(feedLink))
+			}
+		}
+		mirrors[key] = append(mirrors[key], feed)
+	}
+
+	// Pick a URL to represent each set
+	representatives := map[string]string{}
+	for u := range urls {
+		root := sets.find(siteKey(u))
+		current, ok := representatives[root]
+		if !ok || cmpCanonicalPreference(u, current, targets) < 0 {
+			representatives[root] = u
+		}
+	}
+
+	// Group the feeds, keeping a followed feed when possible
+	feedGroups := map[string][]*FeedResult{}
+	for _, feedLink := range sortedKeys(results.Feeds) {
+		root := sets.find(siteKey(feedLink))
+		feedGroups[root] = append(feedGroups[root], results.Feeds[feedLink])
+	}
+	feedIds := map[string]string{}
+	for root, group := range feedGroups {
+		slices.SortFunc(group, func(a, b *FeedResult) int {
+			if a.IsDirect != b.IsDirect {
+				if a.IsDirect {
+					return -1
+				}
+				return 1
+			}
+			return cmpCanonicalPreference(a.Feed.Params.FeedLink, b.Feed.Params.FeedLink, targets)
+		})
+		keeper := group[0]
+		representatives[root] = keeper.Feed.Params.FeedLink
+		for _, duplicate := range group[1:] {
+			log.Printf("Merging duplicate feed %s into %s", duplicate.Feed.Params.FeedLink, keeper.Feed.Params.FeedLink)
+			keeper.IsDirect = keeper.IsDirect || duplicate.IsDirect
+			keeper.Feed.WithAlias(duplicate.Feed.Params.FeedLink)
+			feedIds[duplicate.Feed.Params.Id] = keeper.Feed.Params.Id
+			delete(results.Feeds, duplicate.Feed.Params.FeedLink)
+		}
+	}
+
+	canonical := func(u string) string {
+		if rep, ok := representatives[sets.find(siteKey(u))]; ok {
+			return rep
+		}
+		return u
+	}
+
+	for u := range urls {
+		if canonical(u) != u {
+			results.Canonicals[u] = canonical(u)
+		}
+	}
+
+	for _, found := range results.Feeds {
+		if found.Feed.Params.Link != "" {
+			found.Feed.WithLink(canonical(found.Feed.Params.Link))
+		}
+	}
+
+	// Point the network at the canonical URLs
+	links := map[string]*LinkFrontmatter{}
+	for _, id := range sortedKeys(results.Links) {
+		link := results.Links[id]
+		link.Params.SourceURL = canonical(link.Params.SourceURL)
+		link.Params.DestinationURL = canonical(link.Params.DestinationURL)
+		if link.Params.SourceURL == link.Params.DestinationURL {
+			// Links between duplicates aren't interesting
+			continue
+		}
+		newId := buildLinkId(link.Params.SourceURL, link.Params.DestinationURL)
+		if _, ok := links[newId]; !ok {
+			links[newId] = link
+		}
+	}
+	results.Links = links
+
+	// Posts follow their feed, dropping posts both duplicates had
+	seenPosts := map[string]bool{}
+	for _, id := range sortedKeys(results.Posts) {
+		post := results.Posts[id]
+		if feedId, ok := feedIds[post.Params.FeedId]; ok {
+			post.Params.FeedId = feedId
+		}
+		key := post.Params.FeedId + "\n" + post.Params.Link
+		if seenPosts[key] {
+			delete(results.Posts, id)
+			continue
+		}
+		seenPosts[key] = true
+	}
+}
+
+func sharesAny(a, b []string) bool {
+	for _, item := range a {
+		if slices.Contains(b, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+)
+
+type CategoryRules struct {
+	// Treat "Go" and "go" as the same category
+	CaseFolding *bool `yaml:"case_folding"`
+	// Split "Programming/Go" into "Programming" and "Go"
+	SplitPaths *bool `yaml:"split_paths"`
+	// Category -> other names for it
+	Synonyms map[string][]string `yaml:"synonyms"`
+	Block    []string            `yaml:"block"`
+	Allow    []string            `yaml:"allow"`
+}
+
+type ParsedCategoryRules struct {
+	CaseFolding bool
+	SplitPaths  bool
+	// Folded name -> category
+	synonyms map[string]string
+	block    []string
+	allow    []string
+}
+
+func (r *CategoryRules) Parse() *ParsedCategoryRules {
+	if r == nil {
+		r = &CategoryRules{}
+	}
+	out := &ParsedCategoryRules{
+		CaseFolding: boolDefault(r.CaseFolding, false),
+		SplitPaths:  boolDefault(r.SplitPaths, false),
+		synonyms:    map[string]string{},
+	}
+	for category, names := range r.Synonyms {
+		for _, name := range names {
+			out.synonyms[strings.ToLower(strings.TrimSpace(name))] = category
+		}
+	}
+	// Block and allow lists name categories after normalization
+	out.block = out.Normalize(r.Block)
+	out.allow = out.Normalize(r.Allow)
+	return out
+}
+
+// Clean up categories found in a feed
+func (r *ParsedCategoryRules) Normalize(categories []string) []string {
+	out := []string{}
+	for _, category := range categories {
+		parts := []string{category}
+		if r.SplitPaths {
+			parts = strings.Split(category, "/")
+		}
+		for _, part := range parts {
+			part = strings.Join(strings.Fields(part), " ")
+			if part == "" {
+				continue
+			}
+			if synonym, ok := r.synonyms[strings.ToLower(part)]; ok {
+				part = synonym
+			}
+			if r.CaseFolding {
+				part = strings.ToLower(part)
+			}
+			if !slices.Contains(out, part) {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// Returns the blocked category, if any
+func (r *ParsedCategoryRules) Blocks(categories []string) (bool, string) {
+	for _, category := range categories {
+		if slices.Contains(r.block, category) {
+			return true, category
+		}
+	}
+	return false, ""
+}
+
+// Returns the category that keeps the post out
+func (r *ParsedCategoryRules) Excludes(categories []string) (bool, string) {
+	if blocked, category := r.Blocks(categories); blocked {
+		return true, category
+	}
+	if len(r.allow) == 0 {
+		return false, ""
+	}
+	for _, category := range categories {
+		if slices.Contains(r.allow, category) {
+			return false, ""
+		}
+	}
+	return true, "(not allowed)"
+}
+
+// Check normalized categories against the block and allow lists
+// Many feeds have no categories, so allow only applies to posts
+func (c *Crawler) isBlockedCategory(kind, link, title string, categories []string) bool {
+	excluded, category := c.Config.Categories.Blocks(categories)
+	if kind == "post" {
+		excluded, category = c.Config.Categories.Excludes(categories)
+	}
+	if !excluded {
+		return false
+	}
+	log.Printf("Blocked %s by category %s: %s", kind, category, link)
+	c.TrackModeration(ModerationEntry{
+		Kind:  kind,
+		Link:  link,
+		Title: title,
+		Rule:  "category:" + category,
+		Field: BLOCK_FIELD_CATEGORIES,
+	})
+	return true
+}
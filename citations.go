@@ -0,0 +1,95 @@
+package main
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// The home page of the site a link is on
+func siteRoot(link string) (string, bool) {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", false
+	}
+	return parsed.Scheme + "://" + strings.ToLower(parsed.Host) + "/", true
+}
+
+// Sites linked from a post's HTML, other than the post's own site
+func postCitations(postLink string, htmls ...string) []string {
+	base, err := url.Parse(postLink)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	cited := []string{}
+	for _, html := range htmls {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			continue
+		}
+		doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+			href, err := base.Parse(strings.TrimSpace(a.AttrOr("href", "")))
+			if err != nil {
+				return
+			}
+			site, ok := siteRoot(href.String())
+			if !ok || seen[site] || isSameSite(site, postLink) {
+				return
+			}
+			seen[site] = true
+			cited = append(cited, site)
+		})
+	}
+	return cited
+}
+
+// Record post_cites links from the site of a post to the sites it links to
+//
+// Posts on the feed's website are cited from that website, so citations
+// connect to the feed even when the website isn't at the root of its domain.
+// Sites cited by enough other sites are crawled to discover their feeds.
+func (c *Crawler) TrackCitations(r *colly.Request, website, postLink string, htmls ...string) {
+	if !c.Config.ExtractCitations {
+		return
+	}
+	source, ok := siteRoot(postLink)
+	if !ok {
+		return
+	}
+	if website != "" && isSameSite(postLink, website) {
+		source = website
+	}
+	// Same URLs as requests record, so citations and crawled sites are one node
+	_, source, ok = c.normalizeUrl(source)
+	if !ok {
+		return
+	}
+	for _, cited := range postCitations(postLink, htmls...) {
+		_, cited, ok := c.normalizeUrl(cited)
+		if !ok {
+			continue
+		}
+		if blocked, _ := isBlockedDomain(cited, c.Config); blocked {
+			continue
+		}
+		c.SaveLink(NewLinkFrontmatter(NODE_TYPE_WEBSITE, source, NODE_TYPE_WEBSITE, cited, LINK_TYPE_POST_CITES))
+		if c.trackCitation(source, cited) == c.Config.CitationDiscoveryThreshold {
+			log.Printf("Discovering %s, cited by %d sites", cited, c.Config.CitationDiscoveryThreshold)
+			c.Request(NODE_TYPE_WEBSITE, source, NODE_TYPE_WEBSITE, cited, LINK_TYPE_POST_CITES, r.Depth+1)
+		}
+	}
+}
+
+// Returns the number of sites citing the cited site
+func (c *Crawler) trackCitation(source, cited string) int {
+	c.Results.lock.Lock()
+	defer c.Results.lock.Unlock()
+	if c.Results.Citations[cited] == nil {
+		c.Results.Citations[cited] = map[string]bool{}
+	}
+	c.Results.Citations[cited][source] = true
+	return len(c.Results.Citations[cited])
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Query parameters that only track where a click came from
+// A trailing * matches any suffix
+var DEFAULT_STRIP_QUERY_PARAMS = []string{
+	"utm_*",
+	"ref",
+	"ref_src",
+	"fbclid",
+	"gclid",
+	"dclid",
+	"msclkid",
+	"mc_cid",
+	"mc_eid",
+	"igshid",
+	"_hsenc",
+	"_hsmi",
+	"mkt_tok",
+	"yclid",
+}
+
+// Feed proxies that wrap post links in a redirect
+var DEFAULT_REDIRECTOR_HOSTS = []string{
+	"feedproxy.google.com",
+	"feeds.feedburner.com",
+	"feeds.feedblitz.com",
+	"rss.feedsportal.com",
+}
+
+func isStrippedParam(name string, params []string) bool {
+	name = strings.ToLower(name)
+	for _, param := range params {
+		param = strings.ToLower(param)
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == param {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove tracking parameters, keeping the order of the rest
+func stripTrackingParams(u *url.URL, params []string) {
+	if u.RawQuery == "" {
+		return
+	}
+	kept := []string{}
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if pair != "" && !isStrippedParam(name, params) {
+			kept = append(kept, pair)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+}
+
+// Clean up a post link before it's published
+func (c *Crawler) CleanPostLink(link string) string {
+	if c.Config.ResolveRedirectors && isRedirectorLink(link, c.Config.RedirectorHosts) {
+		link = c.resolveRedirect(link)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil || !parsed.IsAbs() {
+		return link
+	}
+	stripTrackingParams(parsed, c.Config.StripQueryParams)
+	return parsed.String()
+}
+
+func isRedirectorLink(link string, hosts []string) bool {
+	for _, host := range hosts {
+		if isDomainOrSubdomain(link, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Follow a redirector to the page it points at
+// Returns the original link if that doesn't work out
+func (c *Crawler) resolveRedirect(link string) string {
+	if resolved, ok := c.resolvedLinks.Load(link); ok {
+		return resolved.(string)
+	}
+	resolved := link
+	req, err := http.NewRequest(http.MethodHead, link, nil)
+	if err == nil {
+		req.Header.Set("User-Agent", USER_AGENT)
+		var resp *http.Response
+		resp, err = c.HttpClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			resolved = resp.Request.URL.String()
+		}
+	}
+	if err != nil {
+		log.Printf("Unable to resolve redirect %s: %v", link, err)
+	} else if resolved != link {
+		log.Printf("Resolved redirect %s to %s", link, resolved)
+	}
+	c.resolvedLinks.Store(link, resolved)
+	return resolved
+}
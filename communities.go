@@ -0,0 +1,243 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"slices"
+)
+
+const COMMUNITY_MAX_ITERATIONS = 100
+
+// How many categories describe a community
+const COMMUNITY_TOP_CATEGORIES = 5
+
+// Tightly connected feeds, like "IndieWeb" or "Go developers"
+type Community struct {
+	Id    string
+	Feeds []string
+	// Categories shared by at least two feeds, most shared first
+	Categories []CategoryCount
+}
+
+type CategoryCount struct {
+	Category string
+	Feeds    int
+}
+
+// See: https://en.wikipedia.org/wiki/Louvain_method
+//
+// Links are treated as undirected. Each node joins the neighboring
+// community that most improves modularity, then communities are merged
+// into single nodes and the process repeats until nothing moves.
+// Nodes are visited in a fixed order so runs over the same network find
+// the same communities.
+func (r *CrawlResults) louvain() map[string]string {
+	index := map[string]int{}
+	nodes := []string{}
+	// Both directions, so each node's weights sum to its degree
+	weights := []map[int]float64{}
+	nodeIndex := func(url string) int {
+		if i, ok := index[url]; ok {
+			return i
+		}
+		index[url] = len(nodes)
+		nodes = append(nodes, url)
+		weights = append(weights, map[int]float64{})
+		return index[url]
+	}
+	edges := map[[2]string]bool{}
+	for _, id := range sortedKeys(r.Links) {
+		link := r.Links[id]
+		source, destination := link.Params.SourceURL, link.Params.DestinationURL
+		// Seeds link to everything we follow, which would make one big community
+		if !isRecommendation(link) || r.Seeds[source] || r.Seeds[destination] {
+			continue
+		}
+		if source > destination {
+			source, destination = destination, source
+		}
+		edges[[2]string{source, destination}] = true
+	}
+	for _, edge := range sortedEdges(edges) {
+		a, b := nodeIndex(edge[0]), nodeIndex(edge[1])
+		weights[a][b]++
+		weights[b][a]++
+	}
+
+	// Original node -> current node
+	membership := make([]int, len(nodes))
+	for i := range membership {
+		membership[i] = i
+	}
+	for level := 0; level < COMMUNITY_MAX_ITERATIONS; level++ {
+		communities, moved := louvainLocalMoves(weights)
+		if !moved {
+			break
+		}
+		// Number the communities, then merge each into a single node
+		renumber := map[int]int{}
+		for _, community := range communities {
+			if _, ok := renumber[community]; !ok {
+				renumber[community] = len(renumber)
+			}
+		}
+		merged := make([]map[int]float64, len(renumber))
+		for i := range merged {
+			merged[i] = map[int]float64{}
+		}
+		for i, neighbors := range weights {
+			for j, weight := range neighbors {
+				merged[renumber[communities[i]]][renumber[communities[j]]] += weight
+			}
+		}
+		for i := range membership {
+			membership[i] = renumber[communities[membership[i]]]
+		}
+		weights = merged
+	}
+
+	// Name each community after its smallest URL
+	names := map[int]string{}
+	labels := map[string]string{}
+	for i, url := range nodes {
+		if _, ok := names[membership[i]]; !ok || url < names[membership[i]] {
+			names[membership[i]] = url
+		}
+	}
+	for i, url := range nodes {
+		labels[url] = names[membership[i]]
+	}
+	return labels
+}
+
+// Move nodes between communities while modularity improves
+// Returns each node's community, and whether any node moved
+func louvainLocalMoves(weights []map[int]float64) ([]int, bool) {
+	communities := make([]int, len(weights))
+	degrees := make([]float64, len(weights))
+	totals := make([]float64, len(weights))
+	total := 0.0
+	for i, neighbors := range weights {
+		communities[i] = i
+		for _, weight := range neighbors {
+			degrees[i] += weight
+		}
+		totals[i] = degrees[i]
+		total += degrees[i]
+	}
+	if total == 0 {
+		return communities, false
+	}
+
+	moved := false
+	for pass := 0; pass < COMMUNITY_MAX_ITERATIONS; pass++ {
+		changed := false
+		for i, neighbors := range weights {
+			current := communities[i]
+			totals[current] -= degrees[i]
+
+			// Weight from this node into each neighboring community
+			into := map[int]float64{}
+			for j, weight := range neighbors {
+				if j != i {
+					into[communities[j]] += weight
+				}
+			}
+			gain := func(community int) float64 {
+				return into[community] - totals[community]*degrees[i]/total
+			}
+			best, bestGain := current, gain(current)
+			candidates := []int{}
+			for community := range into {
+				candidates = append(candidates, community)
+			}
+			slices.Sort(candidates)
+			for _, community := range candidates {
+				if g := gain(community); g > bestGain+1e-12 {
+					best, bestGain = community, g
+				}
+			}
+
+			totals[best] += degrees[i]
+			if best != current {
+				communities[i] = best
+				changed = true
+				moved = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return communities, moved
+}
+
+func sortedEdges(edges map[[2]string]bool) [][2]string {
+	sorted := [][2]string{}
+	for edge := range edges {
+		sorted = append(sorted, edge)
+	}
+	slices.SortFunc(sorted, func(a, b [2]string) int {
+		if byFirst := cmp.Compare(a[0], b[0]); byFirst != 0 {
+			return byFirst
+		}
+		return cmp.Compare(a[1], b[1])
+	})
+	return sorted
+}
+
+// Group feeds into communities and describe each by its shared categories
+func (c *Crawler) DetectCommunities() {
+	results := c.Results
+	labels := results.louvain()
+
+	communities := map[string]*Community{}
+	for _, feedLink := range sortedKeys(results.Feeds) {
+		label, ok := labels[feedLink]
+		if !ok {
+			// Feeds without links are a community of one
+			label = feedLink
+		}
+		community, ok := communities[label]
+		if !ok {
+			community = &Community{Id: buildSafeId("", label)}
+			communities[label] = community
+		}
+		community.Feeds = append(community.Feeds, feedLink)
+	}
+
+	for _, community := range communities {
+		counts := map[string]int{}
+		for _, feedLink := range community.Feeds {
+			for _, category := range results.Feeds[feedLink].Feed.Params.Categories {
+				counts[category]++
+			}
+		}
+		for category, count := range counts {
+			if count > 1 {
+				community.Categories = append(community.Categories, CategoryCount{category, count})
+			}
+		}
+		slices.SortFunc(community.Categories, func(a, b CategoryCount) int {
+			if byCount := cmp.Compare(b.Feeds, a.Feeds); byCount != 0 {
+				return byCount
+			}
+			return cmp.Compare(a.Category, b.Category)
+		})
+		if len(community.Categories) > COMMUNITY_TOP_CATEGORIES {
+			community.Categories = community.Categories[:COMMUNITY_TOP_CATEGORIES]
+		}
+
+		categories := []string{}
+		for _, category := range community.Categories {
+			categories = append(categories, category.Category)
+		}
+		for _, feedLink := range community.Feeds {
+			results.Feeds[feedLink].Feed.WithCommunity(community.Id, categories)
+		}
+		if len(community.Feeds) > 1 {
+			log.Printf("Community %s has %d feeds, sharing %v", community.Id, len(community.Feeds), categories)
+		}
+		results.Communities[community.Id] = community
+	}
+}
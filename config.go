@@ -14,35 +14,107 @@ type NonOpmlBlogroll struct {
 	Settings string `yaml:"settings"`
 }
 
+// Settings for a single feed, keyed by feed URL or domain
+type FeedOverride struct {
+	MaxPostsPerFeed *int        `yaml:"max_posts_per_feed"`
+	BlockWords      []string    `yaml:"block_words"`
+	BlockRules      []BlockRule `yaml:"block_rules"`
+	Title           *string     `yaml:"title"`
+	Description     *string     `yaml:"description"`
+	Language        *string     `yaml:"language"`
+	Categories      []string    `yaml:"categories"`
+	Priority        *int        `yaml:"priority"`
+	// Keep the feed in the network, but don't show its posts
+	HidePosts *bool `yaml:"hide_posts"`
+
+	// Compiled from BlockWords and BlockRules
+	blockRules []*CompiledBlockRule
+}
 
 type Config struct {
-	FeedUrls []string `yaml:"feed_urls"`
+	FeedUrls        []string          `yaml:"feed_urls"`
 	NonOpmlBlogroll []NonOpmlBlogroll `yaml:"non_opml_blogroll_urls"`
 
 	PrivateBlocksFile string `yaml:"private_blocks_file"`
 
 	// Post limits and filters
-	BlockWords       []string `yaml:"block_words"`
-	BlockDomains     []string `yaml:"block_domains"`
-	BlockPosts       []string `yaml:"block_posts"`
-	PostAgeLimitDays *int     `yaml:"post_age_limit_days"`
-	MaxPostsPerFeed  *int     `yaml:"max_posts_per_feed"`
-	MaxPosts         *int     `yaml:"max_posts"`
+	BlockWords       []string    `yaml:"block_words"`
+	BlockRules       []BlockRule `yaml:"block_rules"`
+	BlockDomains     []string    `yaml:"block_domains"`
+	BlockPosts       []string    `yaml:"block_posts"`
+	PostAgeLimitDays *int        `yaml:"post_age_limit_days"`
+	MaxPostsPerFeed  *int        `yaml:"max_posts_per_feed"`
+	MaxPosts         *int        `yaml:"max_posts"`
+
+	// Tracking parameters to remove from links
+	StripQueryParams []string `yaml:"strip_query_params"`
+	// Follow feed proxy redirects in post links
+	ResolveRedirectors *bool    `yaml:"resolve_redirectors"`
+	RedirectorHosts    []string `yaml:"redirector_hosts"`
+
+	// Dates for posts without one: first_seen, last_modified or feed
+	DateFallbacks []string `yaml:"date_fallbacks"`
+
+	// Posts dated after the fetch time: drop, clamp or keep
+	FuturePosts                 *string `yaml:"future_posts"`
+	FuturePostsToleranceMinutes *int    `yaml:"future_posts_tolerance_minutes"`
+
+	// Category cleanup and filters, for feeds and posts
+	Categories *CategoryRules `yaml:"categories"`
+
+	// Only keep posts in these languages
+	Languages []string `yaml:"languages"`
+
+	// Only keep posts on these topics
+	IncludeFollowing  *IncludeRules `yaml:"include_following"`
+	IncludeDiscovered *IncludeRules `yaml:"include_discovered"`
+
+	// Links in posts between sites
+	ExtractCitations           *bool `yaml:"extract_citations"`
+	CitationDiscoveryThreshold *int  `yaml:"citation_discovery_threshold"`
+
+	FeedOverrides map[string]FeedOverride `yaml:"feed_overrides"`
 
 	// Output modes
 	OutputModes []string `yaml:"output_mode"`
 
+	// Export the network graph for Gephi or your site
+	GraphExportFormats []string `yaml:"graph_export_formats"`
+	GraphExportFolder  *string  `yaml:"graph_export_folder"`
+
+	// Report what changed in the network since the last run
+	DiffReport       *bool   `yaml:"diff_report"`
+	DiffReportFolder *string `yaml:"diff_report_folder"`
+	DiffSnapshotFile *string `yaml:"diff_snapshot_file"`
+
+	// Feeds often listed with the feeds we follow
+	Suggestions       *bool   `yaml:"suggestions"`
+	MaxSuggestions    *int    `yaml:"max_suggestions"`
+	SuggestionsFolder *string `yaml:"suggestions_folder"`
+
 	// Output folders
 	ReadingFolderName   *string `yaml:"reading_folder_name"`
 	FollowingFolderName *string `yaml:"following_folder_name"`
 	DiscoverFolderName  *string `yaml:"discover_folder_name"`
 	NetworkFolderName   *string `yaml:"network_folder_name"`
 	BlogrollFolderName  *string `yaml:"blogroll_folder_name"`
+	IdentityFolderName  *string `yaml:"identity_folder_name"`
 
 	// Should we add content on-top-of existing content
 	// or should we remove and replace it?
 	RemoveOldContent *bool `yaml:"remove_old_content"`
 
+	// Only refetch feeds once their refresh hints allow it
+	IncrementalCrawl *bool `yaml:"incremental_crawl"`
+
+	// Follow RFC 5005 paging links of newly followed feeds
+	BackfillPages *int `yaml:"backfill_pages"`
+
+	// WebSub subscriber, enabled by setting a callback URL
+	WebSubCallbackUrl    *string `yaml:"websub_callback_url"`
+	WebSubListenAddr     *string `yaml:"websub_listen_addr"`
+	WebSubListenDuration *int    `yaml:"websub_listen_duration_ms"`
+
 	// Discovery of recommended feeds
 	DiscoverDepth             *int `yaml:"discover_depth"`
 	PostCollectionDepth       *int `yaml:"post_collection_depth"`
@@ -71,9 +143,10 @@ type Config struct {
 
 type PrivateConfig struct {
 	// Filters not shared with the world
-	BlockWords   []string `yaml:"block_words"`
-	BlockDomains []string `yaml:"block_domains"`
-	BlockPosts   []string `yaml:"block_posts"`
+	BlockWords   []string    `yaml:"block_words"`
+	BlockRules   []BlockRule `yaml:"block_rules"`
+	BlockDomains []string    `yaml:"block_domains"`
+	BlockPosts   []string    `yaml:"block_posts"`
 }
 
 func strDefault(a *string, b string) string {
@@ -122,19 +195,10 @@ func (c *Config) ParseOutputMode() []OutputMode {
 func (c *Config) Parse() *ParsedConfig {
 	out := new(ParsedConfig)
 	out.FeedUrls = c.FeedUrls
-	out.BlockWords = c.BlockWords
+	out.NonOpmlBlogroll = c.NonOpmlBlogroll
+	out.BlockRules = slices.Concat(blockWordRules(c.BlockWords, false), compileBlockRules(c.BlockRules, false))
 	out.BlockDomains = c.BlockDomains
 
-	for _, source := range c.NonOpmlBlogroll {
-		if source.Handler == "jq" {
-			for _, url := range jqProcessUrl(source.Url, source.Settings) {
-				out.FeedUrls = append(out.FeedUrls, url)
-			}
-		}
-	}
-	// We'll likely have duplicates here
-	out.FeedUrls = dedupeSlice(out.FeedUrls)
-
 	out.BlockPosts = make(map[string]bool, len(c.BlockPosts))
 	for _, blockTerm := range c.BlockPosts {
 		out.BlockPosts[blockTerm] = true
@@ -151,27 +215,69 @@ func (c *Config) Parse() *ParsedConfig {
 		err = decoder.Decode(&priv)
 		ohno(err)
 
-		out.BlockWords = append(out.BlockWords, priv.BlockWords...)
+		out.BlockRules = slices.Concat(out.BlockRules, blockWordRules(priv.BlockWords, true), compileBlockRules(priv.BlockRules, true))
 		out.BlockDomains = append(out.BlockDomains, priv.BlockDomains...)
 		for _, blockTerm := range priv.BlockPosts {
 			out.BlockPosts[blockTerm] = true
 		}
 	}
 
+	out.Categories = c.Categories.Parse()
+	out.Languages = c.Languages
+	out.IncludeFollowing = c.IncludeFollowing.Compile()
+	out.IncludeDiscovered = c.IncludeDiscovered.Compile()
+	out.ExtractCitations = boolDefault(c.ExtractCitations, false)
+	out.CitationDiscoveryThreshold = intDefault(c.CitationDiscoveryThreshold, 0)
+
+	out.FeedOverrides = make(map[string]FeedOverride, len(c.FeedOverrides))
+	for key, override := range c.FeedOverrides {
+		override.blockRules = slices.Concat(blockWordRules(override.BlockWords, false), compileBlockRules(override.BlockRules, false))
+		// Forced categories are matched against the normalized block and allow lists
+		override.Categories = out.Categories.Normalize(override.Categories)
+		out.FeedOverrides[key] = override
+	}
+
 	out.OutputModes = c.ParseOutputMode()
+	out.GraphExportFormats = parseGraphFormats(c.GraphExportFormats)
+	out.GraphExportFolder = strDefault(c.GraphExportFolder, DEFAULT_GRAPH_EXPORT_FOLDER)
+	out.DiffReport = boolDefault(c.DiffReport, false)
+	out.DiffReportFolder = strDefault(c.DiffReportFolder, DEFAULT_DIFF_REPORT_FOLDER)
+	out.DiffSnapshotFile = strDefault(c.DiffSnapshotFile, DEFAULT_DIFF_SNAPSHOT_FILE)
+	out.Suggestions = boolDefault(c.Suggestions, false)
+	out.MaxSuggestions = intDefault(c.MaxSuggestions, 50)
+	out.SuggestionsFolder = strDefault(c.SuggestionsFolder, DEFAULT_SUGGESTIONS_FOLDER)
 
 	out.ReadingFolderName = strDefault(c.ReadingFolderName, contentPath(DEFAULT_READING_FOLDER))
 	out.FollowingFolderName = strDefault(c.FollowingFolderName, contentPath(DEFAULT_FOLLOWING_FOLDER))
 	out.DiscoverFolderName = strDefault(c.DiscoverFolderName, contentPath(DEFAULT_DISCOVER_FOLDER))
 	out.NetworkFolderName = strDefault(c.NetworkFolderName, contentPath(DEFAULT_NETWORK_FOLDER))
 	out.BlogrollFolderName = strDefault(c.BlogrollFolderName, contentPath(DEFAULT_BLOGROLL_FOLDER))
+	out.IdentityFolderName = strDefault(c.IdentityFolderName, contentPath(DEFAULT_IDENTITY_FOLDER))
 
 	out.RemoveOldContent = boolDefault(c.RemoveOldContent, true)
+	out.IncrementalCrawl = boolDefault(c.IncrementalCrawl, false)
+	out.BackfillPages = intDefault(c.BackfillPages, 0)
+
+	out.WebSubCallbackUrl = strDefault(c.WebSubCallbackUrl, "")
+	out.WebSubListenAddr = strDefault(c.WebSubListenAddr, "localhost:8080")
+	out.WebSubListenDuration = time.Duration(intDefault(c.WebSubListenDuration, 60000)) * time.Millisecond
 
 	ageLimit := -1 * intDefault(c.PostAgeLimitDays, 36500) // about 100 years ago
 	out.PostAgeLimit = time.Now().AddDate(0, 0, ageLimit)
 
 	out.MaxPosts = intDefault(c.MaxPosts, 1000)
+	out.StripQueryParams = c.StripQueryParams
+	if out.StripQueryParams == nil {
+		out.StripQueryParams = DEFAULT_STRIP_QUERY_PARAMS
+	}
+	out.ResolveRedirectors = boolDefault(c.ResolveRedirectors, false)
+	out.RedirectorHosts = c.RedirectorHosts
+	if out.RedirectorHosts == nil {
+		out.RedirectorHosts = DEFAULT_REDIRECTOR_HOSTS
+	}
+	out.DateFallbacks = parseDateFallbacks(c.DateFallbacks)
+	out.FuturePosts = parseFuturePostsPolicy(c.FuturePosts)
+	out.FuturePostsTolerance = time.Duration(intDefault(c.FuturePostsToleranceMinutes, 1440)) * time.Minute
 	out.MaxPostsPerFeed = intDefault(c.MaxPostsPerFeed, 100)
 	out.DiscoverDepth = intDefault(c.DiscoverDepth, 4)
 	out.PostCollectionDepth = intDefault(c.PostCollectionDepth, 2)
@@ -199,26 +305,63 @@ func (c *Config) Parse() *ParsedConfig {
 }
 
 type ParsedConfig struct {
-	FeedUrls []string
-
+	FeedUrls        []string
+	NonOpmlBlogroll []NonOpmlBlogroll
 
-	BlockWords   []string
+	BlockRules   []*CompiledBlockRule
 	BlockDomains []string
 
 	BlockPosts map[string]bool
 
+	Categories *ParsedCategoryRules
+	Languages  []string
+
+	IncludeFollowing  *CompiledIncludeRules
+	IncludeDiscovered *CompiledIncludeRules
+
+	ExtractCitations           bool
+	CitationDiscoveryThreshold int
+
+	FeedOverrides map[string]FeedOverride
+
 	OutputModes []OutputMode
 
+	GraphExportFormats []string
+	GraphExportFolder  string
+
+	DiffReport       bool
+	DiffReportFolder string
+	DiffSnapshotFile string
+
+	Suggestions       bool
+	MaxSuggestions    int
+	SuggestionsFolder string
+
 	ReadingFolderName   string
 	FollowingFolderName string
 	DiscoverFolderName  string
 	NetworkFolderName   string
 	BlogrollFolderName  string
+	IdentityFolderName  string
 
 	RemoveOldContent bool
+	IncrementalCrawl bool
+	BackfillPages    int
+
+	WebSubCallbackUrl    string
+	WebSubListenAddr     string
+	WebSubListenDuration time.Duration
 
 	PostAgeLimit time.Time
 
+	StripQueryParams   []string
+	ResolveRedirectors bool
+	RedirectorHosts    []string
+
+	DateFallbacks        []string
+	FuturePosts          string
+	FuturePostsTolerance time.Duration
+
 	MaxPosts                  int
 	MaxPostsPerFeed           int
 	DiscoverDepth             int
@@ -243,6 +386,31 @@ type ParsedConfig struct {
 	HttpOnlyHosts []string
 }
 
+// Find the override for a feed, given its URLs
+// An exact URL match wins, then the most specific domain
+func (c *ParsedConfig) OverrideFor(urls ...string) FeedOverride {
+	for _, url := range urls {
+		if override, ok := c.FeedOverrides[url]; ok {
+			return override
+		}
+	}
+	found := FeedOverride{}
+	foundDomain := ""
+	for key, override := range c.FeedOverrides {
+		if len(key) <= len(foundDomain) {
+			continue
+		}
+		for _, url := range urls {
+			if isDomainOrSubdomain(url, key) {
+				found = override
+				foundDomain = key
+				break
+			}
+		}
+	}
+	return found
+}
+
 func (c *ParsedConfig) BuildTransport() *http.Transport {
 	// Defaults: https://pkg.go.dev/net/http#DefaultTransport
 	d := &net.Dialer{
@@ -7,15 +7,17 @@ const DEFAULT_FOLLOWING_FOLDER = "following"
 const DEFAULT_DISCOVER_FOLDER = "discover"
 const DEFAULT_NETWORK_FOLDER = "network"
 const DEFAULT_BLOGROLL_FOLDER = "blogroll"
+const DEFAULT_IDENTITY_FOLDER = "identity"
 
 const POST_PREFIX = "post-"
 const FEED_PREFIX = "feed-"
 const LINK_PREFIX = "link-"
 const BLOGROLL_PREFIX = "br-"
+const IDENTITY_PREFIX = "id-"
 
-// This uses a float as a workaround for Go-Colly
-// marshaling, which converts int to float
-type NodeType = float64
+// The role a URL plays in the network
+// Request contexts keep it as a string, see putNodeType
+type NodeType int
 
 const (
 	NODE_TYPE_SEED NodeType = iota
@@ -25,6 +27,22 @@ const (
 	NODE_TYPE_CANONICAL
 )
 
+func (t NodeType) String() string {
+	switch t {
+	case NODE_TYPE_SEED:
+		return "seed"
+	case NODE_TYPE_FEED:
+		return "feed"
+	case NODE_TYPE_WEBSITE:
+		return "website"
+	case NODE_TYPE_BLOGROLL:
+		return "blogroll"
+	case NODE_TYPE_CANONICAL:
+		return "canonical"
+	}
+	return "unknown"
+}
+
 type OutputMode = string
 
 const (
@@ -70,6 +88,8 @@ const (
 	LINK_TYPE_FROM_FEED          = "from_feed"
 	LINK_TYPE_FROM_OPML          = "from_opml"
 	LINK_TYPE_LINK_REL_CANONICAL = "rel_canonical"
+	LINK_TYPE_LINK_REL_SELF      = "rel_self"
+	LINK_TYPE_POST_CITES         = "post_cites"
 )
 
 var META_ROBOT_NOINDEX_VARIANTS = []string{
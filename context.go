@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/gocolly/colly/v2"
+	"strconv"
+	"time"
+)
+
+// Request context keys
+const (
+	CTX_RECOMMENDER      = "rec"
+	CTX_RECOMMENDER_TYPE = "rec_type"
+	CTX_TARGET_TYPE      = "target_type"
+	// The feed a backfill page belongs to, and the URL it was requested by
+	CTX_BACKFILL_OF   = "backfill_of"
+	CTX_BACKFILL_FROM = "backfill_from"
+	CTX_BACKFILL_PAGE = "backfill_page"
+	// The feed's rel=self link, when it identifies as another URL
+	CTX_SELF_URL = "self_url"
+	// When a reused previous fetch was made
+	CTX_FETCHED_AT    = "fetched_at"
+	CTX_LAST_MODIFIED = "last_modified"
+	// The non-OPML blogroll handler and its settings
+	CTX_HANDLER  = "handler"
+	CTX_SETTINGS = "settings"
+)
+
+// Queued requests have their context marshaled as JSON, which turns
+// numbers into floats, so numbers are kept as strings
+func putInt(ctx *colly.Context, key string, value int) {
+	ctx.Put(key, strconv.Itoa(value))
+}
+
+func getInt(ctx *colly.Context, key string) (int, bool) {
+	value, err := strconv.Atoi(ctx.Get(key))
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+func putTime(ctx *colly.Context, key string, value time.Time) {
+	ctx.Put(key, value.Format(time.RFC3339))
+}
+
+func getTime(ctx *colly.Context, key string) (time.Time, bool) {
+	value, err := time.Parse(time.RFC3339, ctx.Get(key))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return value, true
+}
+
+func putNodeType(ctx *colly.Context, key string, nodeType NodeType) {
+	putInt(ctx, key, int(nodeType))
+}
+
+func getNodeType(ctx *colly.Context, key string) (NodeType, bool) {
+	value, ok := getInt(ctx, key)
+	if !ok {
+		return NODE_TYPE_SEED, false
+	}
+	return NodeType(value), true
+}
+
+// What the request expects to find, like a feed or website
+// Seeds are visited directly, so they don't have one
+func targetTypeOf(r *colly.Request) (NodeType, bool) {
+	return getNodeType(r.Ctx, CTX_TARGET_TYPE)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/antchfx/xmlquery"
 	"github.com/antchfx/xpath"
 	"github.com/gocolly/colly/v2"
@@ -9,18 +10,31 @@ import (
 	"github.com/goware/urlx"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Crawler struct {
-	Collector                        *colly.Collector
-	Config                           *ParsedConfig
-	Queue                            *queue.Queue
-	BlogrollWithNamespaceXPath       *xpath.Expr
-	ITunesCategoryWithNamespaceXPath *xpath.Expr
-	db                               *DB
+	Collector                           *colly.Collector
+	Config                              *ParsedConfig
+	Queue                               *queue.Queue
+	BlogrollWithNamespaceXPath          *xpath.Expr
+	ITunesCategoryWithNamespaceXPath    *xpath.Expr
+	SyUpdatePeriodWithNamespaceXPath    *xpath.Expr
+	SyUpdateFrequencyWithNamespaceXPath *xpath.Expr
+	HubLinkWithNamespaceXPath           *xpath.Expr
+	SelfLinkWithNamespaceXPath          *xpath.Expr
+	DcCreatorWithNamespaceXPath         *xpath.Expr
+	PagingLinkWithNamespaceXPaths       []*xpath.Expr
+	HttpClient                          *http.Client
+	WebSub                              *WebSubSubscriber
+	Results                             *CrawlResults
+	resolvedLinks                       sync.Map
+	db                                  *DB
 }
 
 func (c *Crawler) OnErrorHandler(resp *colly.Response, err error) {
@@ -50,6 +64,9 @@ func OnRequestHandler(r *colly.Request) {
 }
 
 func (c *Crawler) Crawl(urls ...string) {
+	urls = append(urls, c.CollectNonOpmlBlogrolls()...)
+	// We'll likely have duplicates here
+	urls = dedupeSlice(urls)
 	for _, url := range urls {
 		c.Collector.Visit(url)
 	}
@@ -59,19 +76,20 @@ func (c *Crawler) Crawl(urls ...string) {
 	}
 }
 
+// Seed URLs are visited directly, rather than requested from another node
+func isSeedRequest(r *colly.Request) bool {
+	return r.Depth <= 1
+}
+
 func (c *Crawler) PurgeNoIndex() {
 	c.db.DeleteNoIndexLinks()
 }
 
-func (c *Crawler) Request(recommender_type NodeType, recommender string, target_type NodeType, target string, link_type string, depth int) {
-	// Common parsing issue
-	if strings.HasPrefix(target, "mailto:") {
-		return
-	}
-
+// The URL to request for a target, and the normalized URL links record
+func (c *Crawler) normalizeUrl(target string) (*url.URL, string, bool) {
 	parsed, err := urlx.Parse(target)
 	if err != nil {
-		return
+		return nil, "", false
 	}
 
 	// Upgrade HTTP to HTTPS
@@ -86,17 +104,32 @@ func (c *Crawler) Request(recommender_type NodeType, recommender string, target_
 
 	// prevent file:// and other schemes
 	if parsed.Scheme != "http" && parsed.Scheme != "https" {
-		return
+		return nil, "", false
 	}
 
+	stripTrackingParams(parsed, c.Config.StripQueryParams)
+
 	// Normalize URL
-	target, err = urlx.Normalize(parsed)
+	normalized, err := urlx.Normalize(parsed)
 	if err != nil {
+		return nil, "", false
+	}
+	return parsed, normalized, true
+}
+
+func (c *Crawler) Request(recommender_type NodeType, recommender string, target_type NodeType, target string, link_type string, depth int) {
+	// Common parsing issue
+	if strings.HasPrefix(target, "mailto:") {
+		return
+	}
+
+	parsed, target, ok := c.normalizeUrl(target)
+	if !ok {
 		return
 	}
 
 	if blocked, domain := isBlockedDomain(target, c.Config); blocked {
-		log.Printf("Skipping blocked domain: %d", domain)
+		log.Printf("Skipping blocked domain: %s", domain)
 		return
 	}
 
@@ -105,9 +138,9 @@ func (c *Crawler) Request(recommender_type NodeType, recommender string, target_
 	c.SaveLink(link)
 
 	ctx := colly.NewContext()
-	ctx.Put("rec", recommender)
-	ctx.Put("rec_type", recommender_type)
-	ctx.Put("target_type", target_type)
+	ctx.Put(CTX_RECOMMENDER, recommender)
+	putNodeType(ctx, CTX_RECOMMENDER_TYPE, recommender_type)
+	putNodeType(ctx, CTX_TARGET_TYPE, target_type)
 	r := &colly.Request{
 		URL:    parsed,
 		Method: "GET",
@@ -125,12 +158,31 @@ func processXmlQuery(headers *http.Header, r *colly.Request, xpathStr string, na
 }
 
 func (c *Crawler) OnResponseHandler(resp *colly.Response) {
+	c.handleResponse(resp, false)
+}
+
+func (c *Crawler) handleResponse(resp *colly.Response, isReplay bool) {
 	r := resp.Request
 	page_url := r.URL.String()
 	if resp.StatusCode != 200 {
 		return
 	}
 
+	// Backfill pages keep the depth of their feed, but aren't seeds
+	if isSeedRequest(r) && !isBackfillPage(r) {
+		c.TrackSeed(page_url)
+	}
+
+	// Written when publishing, once duplicates are merged
+	// Backfill pages are part of the feed they were found from
+	if !isReplay && !isBackfillPage(r) {
+		if nodeType, ok := targetTypeOf(r); ok {
+			c.TrackNodeFetch(page_url, nodeType, time.Now())
+		} else if isSeedRequest(r) {
+			c.TrackNodeFetch(page_url, NODE_TYPE_SEED, time.Now())
+		}
+	}
+
 	headers := resp.Headers
 	if headers != nil {
 		for _, headerVal := range resp.Headers.Values("X-Robots-Tag") {
@@ -152,13 +204,19 @@ func (c *Crawler) OnResponseHandler(resp *colly.Response) {
 		return
 	}
 
+	if c.Config.IncrementalCrawl && !isReplay {
+		c.TrackFetch(resp, doc)
+	}
+
+	trackLastModified(r, headers)
 	processXmlQuery(headers, r, "/opml", doc, c.OnXML_Opml)
 	processXmlQuery(headers, r, "/rss/channel", doc, c.OnXML_RssChannel)
 	processXmlQuery(headers, r, "/feed", doc, c.OnXML_AtomFeed)
 }
 
-func NewCrawler(config *ParsedConfig) Crawler {
-	crawler := Crawler{}
+func NewCrawler(config *ParsedConfig) *Crawler {
+	// Callbacks are bound to this instance, so share it by pointer
+	crawler := &Crawler{}
 	crawler.Config = config
 	crawler.db = NewDB()
 
@@ -166,6 +224,9 @@ func NewCrawler(config *ParsedConfig) Crawler {
 	nsMap := map[string]string{
 		"source": "http://source.scripting.com/",
 		"itunes": "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		"sy":     "http://purl.org/rss/1.0/modules/syndication/",
+		"atom":   "http://www.w3.org/2005/Atom",
+		"dc":     "http://purl.org/dc/elements/1.1/",
 	}
 	crawler.BlogrollWithNamespaceXPath, err = xpath.CompileWithNS("source:blogroll", nsMap)
 	if err != nil {
@@ -177,6 +238,39 @@ func NewCrawler(config *ParsedConfig) Crawler {
 		panic(err)
 	}
 
+	crawler.SyUpdatePeriodWithNamespaceXPath, err = xpath.CompileWithNS("sy:updatePeriod", nsMap)
+	if err != nil {
+		panic(err)
+	}
+
+	crawler.SyUpdateFrequencyWithNamespaceXPath, err = xpath.CompileWithNS("sy:updateFrequency", nsMap)
+	if err != nil {
+		panic(err)
+	}
+
+	crawler.HubLinkWithNamespaceXPath, err = xpath.CompileWithNS("atom:link[@rel='hub']", nsMap)
+	if err != nil {
+		panic(err)
+	}
+
+	crawler.SelfLinkWithNamespaceXPath, err = xpath.CompileWithNS("atom:link[@rel='self']", nsMap)
+	if err != nil {
+		panic(err)
+	}
+
+	crawler.DcCreatorWithNamespaceXPath, err = xpath.CompileWithNS("dc:creator", nsMap)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, rel := range PAGING_LINK_RELS {
+		expr, err := xpath.CompileWithNS(fmt.Sprintf("atom:link[@rel='%s']", rel), nsMap)
+		if err != nil {
+			panic(err)
+		}
+		crawler.PagingLinkWithNamespaceXPaths = append(crawler.PagingLinkWithNamespaceXPaths, expr)
+	}
+
 	workingDir, err := os.Getwd()
 	if err != nil {
 		panic(err)
@@ -197,10 +291,18 @@ func NewCrawler(config *ParsedConfig) Crawler {
 	}
 
 	crawler.Collector.IgnoreRobotsTxt = false
+	// For requests made outside of the collector
+	crawler.HttpClient = &http.Client{Transport: t}
 	if config.RequestTimeout != nil {
 		crawler.Collector.SetRequestTimeout(*config.RequestTimeout)
+		crawler.HttpClient.Timeout = *config.RequestTimeout
 	}
+	crawler.WebSub = NewWebSubSubscriber()
+	crawler.Results = NewCrawlResults()
 	crawler.Collector.OnRequest(OnRequestHandler)
+	if config.IncrementalCrawl {
+		crawler.Collector.OnRequest(crawler.OnIncrementalRequest)
+	}
 	crawler.Collector.OnError(crawler.OnErrorHandler)
 
 	// XML handled here: OPML, RSS, Atom
@@ -209,59 +311,24 @@ func NewCrawler(config *ParsedConfig) Crawler {
 	// HTML pages
 	crawler.Collector.OnHTML("html", crawler.OnHTML)
 
-	crawler.Queue, err = queue.New(
+	crawler.Queue = newQueue(config)
+	return crawler
+}
+
+func newQueue(config *ParsedConfig) *queue.Queue {
+	q, err := queue.New(
 		config.CrawlThreads,
 		&queue.InMemoryQueueStorage{MaxSize: 10000},
 	)
 	if err != nil {
 		panic(err)
 	}
-
-	return crawler
-}
-
-func (c *Crawler) SaveLink(f *LinkFrontmatter) {
-	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_HUGO_CONTENT) {
-		id := buildLinkId(f.Params.SourceURL, f.Params.DestinationURL)
-		path := generatedFilePath(c.Config.NetworkFolderName, LINK_PREFIX, id)
-		writeYaml(f, path)
-	}
-	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
-		c.db.TrackLink(f)
-	}
-}
-
-func (c *Crawler) SaveFeed(f *FeedFrontmatter, isDirect bool) {
-	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_HUGO_CONTENT) {
-		var path string
-		if isDirect {
-			path = generatedFilePath(c.Config.FollowingFolderName, FEED_PREFIX, f.Params.Id)
-		} else {
-			path = generatedFilePath(c.Config.DiscoverFolderName, FEED_PREFIX, f.Params.Id)
-		}
-		writeYaml(f, path)
-	}
-	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
-		c.db.TrackFeed(f)
-	}
+	return q
 }
 
-func (c *Crawler) SavePost(f *PostFrontmatter) {
-	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_HUGO_CONTENT) {
-		path := generatedFilePath(c.Config.ReadingFolderName, POST_PREFIX, f.Params.Id)
-		writeYaml(f, path)
-	}
-	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
-		c.db.TrackPost(f)
-	}
-}
-
-func (c *Crawler) SaveBlogroll(f *BlogrollFrontmatter) {
-	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_HUGO_CONTENT) {
-		path := generatedFilePath(c.Config.BlogrollFolderName, BLOGROLL_PREFIX, f.Params.Id)
-		writeYaml(f, path)
-	}
+func (c *Crawler) SaveSourceError(url, handler string, err error) {
+	log.Printf("Blogroll source error: %s (%s): %v", url, handler, err)
 	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
-		c.db.TrackBlogroll(f)
+		c.db.TrackSourceError(url, handler, err)
 	}
 }
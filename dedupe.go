@@ -0,0 +1,153 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Titles shorter than this are too generic to match on, like "Weekly notes"
+const DEDUPE_MIN_TITLE_WORDS = 4
+
+// Posts with the same title this far apart are different posts
+const DEDUPE_TITLE_WINDOW = 7 * 24 * time.Hour
+
+// Lowercase words, without punctuation
+func normalizeTitle(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) < DEDUPE_MIN_TITLE_WORDS {
+		return ""
+	}
+	return strings.Join(words, " ")
+}
+
+func datesWithin(a, b string, window time.Duration) bool {
+	aDate, aErr := ParseDate(a)
+	bDate, bErr := ParseDate(b)
+	if aErr != nil || bErr != nil {
+		return false
+	}
+	return aDate.Sub(bDate).Abs() <= window
+}
+
+// The same article is often in the author's feed, a planet and a linkblog
+// Keep one copy, noting the other feeds it was seen in
+func (c *Crawler) DedupePosts() {
+	results := c.Results
+	feedsById := map[string]*FeedResult{}
+	for _, found := range results.Feeds {
+		feedsById[found.Feed.Params.Id] = found
+	}
+
+	// Post ID -> cluster
+	sets := unionFind{}
+	byKey := map[string]string{}
+	byTitle := map[string][]string{}
+	ids := sortedKeys(results.Posts)
+	for _, id := range ids {
+		post := results.Posts[id]
+		keys := []string{"link:" + siteKey(post.Params.Link)}
+		if isWebLink(post.guid) {
+			// A permalink GUID points at the original
+			keys = append(keys, "link:"+siteKey(post.guid))
+		}
+		for _, key := range keys {
+			if other, ok := byKey[key]; ok {
+				sets.union(id, other)
+			} else {
+				byKey[key] = id
+			}
+		}
+		if title := normalizeTitle(post.Title); title != "" {
+			for _, other := range byTitle[title] {
+				if datesWithin(post.Date, results.Posts[other].Date, DEDUPE_TITLE_WINDOW) {
+					sets.union(id, other)
+				}
+			}
+			byTitle[title] = append(byTitle[title], id)
+		}
+	}
+
+	clusters := map[string][]*PostFrontmatter{}
+	for _, id := range ids {
+		root := sets.find(id)
+		clusters[root] = append(clusters[root], results.Posts[id])
+	}
+
+	for _, root := range sortedKeys(clusters) {
+		posts := clusters[root]
+		if len(posts) < 2 {
+			continue
+		}
+		// Copies may say where the original is with a permalink GUID
+		permalinks := map[string]bool{}
+		for _, post := range posts {
+			if isWebLink(post.guid) {
+				permalinks[siteKey(post.guid)] = true
+			}
+		}
+		slices.SortFunc(posts, func(a, b *PostFrontmatter) int {
+			return cmpOriginalPost(a, b, feedsById, permalinks)
+		})
+		keeper := posts[0]
+		for _, post := range posts[1:] {
+			if found, ok := feedsById[post.Params.FeedId]; ok && post.Params.FeedId != keeper.Params.FeedId {
+				keeper.WithAlsoSeenIn(found.Feed.Params.FeedLink)
+			}
+			log.Printf("Post %s is a duplicate of %s", post.Params.Link, keeper.Params.Link)
+			delete(results.Posts, post.Params.Id)
+		}
+	}
+}
+
+// Which of two copies of a post is the original
+func cmpOriginalPost(a, b *PostFrontmatter, feedsById map[string]*FeedResult, permalinks map[string]bool) int {
+	aFeed, bFeed := feedsById[a.Params.FeedId], feedsById[b.Params.FeedId]
+	// Prefer feeds we follow
+	aDirect := aFeed != nil && aFeed.IsDirect
+	bDirect := bFeed != nil && bFeed.IsDirect
+	if aDirect != bDirect {
+		if aDirect {
+			return -1
+		}
+		return 1
+	}
+	// Then the post a permalink points at
+	aPermalink := permalinks[siteKey(a.Params.Link)]
+	bPermalink := permalinks[siteKey(b.Params.Link)]
+	if aPermalink != bPermalink {
+		if aPermalink {
+			return -1
+		}
+		return 1
+	}
+	// Then the feed of the site the post links to
+	aOwn := aFeed != nil && isOwnPost(a, aFeed.Feed)
+	bOwn := bFeed != nil && isOwnPost(b, bFeed.Feed)
+	if aOwn != bOwn {
+		if aOwn {
+			return -1
+		}
+		return 1
+	}
+	// Then whoever published it first
+	byDate := cmpDateStr(a.Date, b.Date)
+	if byDate != 0 {
+		return byDate
+	}
+	return cmp.Compare(a.Params.Id, b.Params.Id)
+}
+
+// Posts on the same site as their feed
+func isOwnPost(post *PostFrontmatter, feed *FeedFrontmatter) bool {
+	site := feed.Params.Link
+	if site == "" {
+		site = feed.Params.FeedLink
+	}
+	return isSameSite(post.Params.Link, site)
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const DEFAULT_DIFF_REPORT_FOLDER = "static/diff"
+const DEFAULT_DIFF_SNAPSHOT_FILE = "feed2pages-snapshot.json"
+
+// The network as published, kept until the next run
+type NetworkSnapshot struct {
+	CreatedAt time.Time                   `json:"created_at"`
+	Feeds     map[string]SnapshotFeed     `json:"feeds"`     // By feed link
+	Blogrolls map[string]SnapshotBlogroll `json:"blogrolls"` // By blogroll link
+	Links     map[string]SnapshotLink     `json:"links"`     // By link ID
+	Posts     map[string]SnapshotPost     `json:"posts"`     // By post ID
+	// Followed feed link -> feed links it recommends
+	Recommendations map[string][]string `json:"recommendations"`
+}
+
+type SnapshotFeed struct {
+	Link      string `json:"link"`
+	Title     string `json:"title"`
+	Following bool   `json:"following"`
+}
+
+type SnapshotBlogroll struct {
+	Link  string   `json:"link"`
+	Title string   `json:"title"`
+	Feeds []string `json:"feeds"`
+}
+
+type SnapshotLink struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	LinkType    string `json:"link_type"`
+}
+
+type SnapshotPost struct {
+	Link  string `json:"link"`
+	Title string `json:"title"`
+	Feed  string `json:"feed"`
+}
+
+// Feeds added to or dropped from a blogroll
+type BlogrollChange struct {
+	Link    string   `json:"link"`
+	Title   string   `json:"title"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type RecommendationChange struct {
+	Feed        SnapshotFeed `json:"feed"`
+	Recommended SnapshotFeed `json:"recommended"`
+}
+
+type NetworkDiff struct {
+	Since              time.Time              `json:"since"`
+	Until              time.Time              `json:"until"`
+	NewFeeds           []SnapshotFeed         `json:"new_feeds"`
+	RemovedFeeds       []SnapshotFeed         `json:"removed_feeds"`
+	NewlyFollowed      []SnapshotFeed         `json:"newly_followed"`
+	Unfollowed         []SnapshotFeed         `json:"unfollowed"`
+	NewBlogrolls       []SnapshotBlogroll     `json:"new_blogrolls"`
+	RemovedBlogrolls   []SnapshotBlogroll     `json:"removed_blogrolls"`
+	BlogrollChanges    []BlogrollChange       `json:"blogroll_changes"`
+	NewRecommendations []RecommendationChange `json:"new_recommendations"`
+	NewLinks           []SnapshotLink         `json:"new_links"`
+	RemovedLinks       []SnapshotLink         `json:"removed_links"`
+	NewPosts           []SnapshotPost         `json:"new_posts"`
+	RemovedPosts       int                    `json:"removed_posts"`
+}
+
+func (r *CrawlResults) Snapshot() *NetworkSnapshot {
+	snapshot := &NetworkSnapshot{
+		CreatedAt:       time.Now().UTC(),
+		Feeds:           map[string]SnapshotFeed{},
+		Blogrolls:       map[string]SnapshotBlogroll{},
+		Links:           map[string]SnapshotLink{},
+		Posts:           map[string]SnapshotPost{},
+		Recommendations: map[string][]string{},
+	}
+	feedsById := map[string]string{}
+	for url, found := range r.Feeds {
+		snapshot.Feeds[url] = SnapshotFeed{
+			Link:      url,
+			Title:     found.Feed.Title,
+			Following: found.IsDirect,
+		}
+		feedsById[found.Feed.Params.Id] = url
+		if found.IsDirect {
+			recommended := r.recommendedFrom(url)
+			slices.Sort(recommended)
+			snapshot.Recommendations[url] = recommended
+		}
+	}
+	for url, blogroll := range r.Blogrolls {
+		feeds := []string{}
+		for _, outline := range blogroll.Params.Outlines {
+			if outline.XmlUrl != "" {
+				feeds = append(feeds, outline.XmlUrl)
+			}
+		}
+		slices.Sort(feeds)
+		snapshot.Blogrolls[url] = SnapshotBlogroll{
+			Link:  url,
+			Title: blogroll.Title,
+			Feeds: slices.Compact(feeds),
+		}
+	}
+	for id, link := range r.Links {
+		snapshot.Links[id] = SnapshotLink{
+			Source:      link.Params.SourceURL,
+			Destination: link.Params.DestinationURL,
+			LinkType:    link.Params.LinkType,
+		}
+	}
+	for id, post := range r.Posts {
+		snapshot.Posts[id] = SnapshotPost{
+			Link:  post.Params.Link,
+			Title: post.Title,
+			Feed:  feedsById[post.Params.FeedId],
+		}
+	}
+	return snapshot
+}
+
+// Returns nil when there's no usable snapshot
+func readSnapshot(path string) *NetworkSnapshot {
+	input, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	ohno(err)
+	snapshot := &NetworkSnapshot{}
+	if err := json.Unmarshal(input, snapshot); err != nil {
+		log.Printf("Ignoring unreadable snapshot %s: %v", path, err)
+		return nil
+	}
+	return snapshot
+}
+
+func writeJson(o any, path string) {
+	output, err := json.MarshalIndent(o, "", "  ")
+	ohno(err)
+	err = os.WriteFile(path, append(output, '\n'), os.FileMode(0644))
+	ohno(err)
+}
+
+// What changed between two runs
+func diffSnapshots(before, after *NetworkSnapshot) *NetworkDiff {
+	diff := &NetworkDiff{
+		Since:              before.CreatedAt,
+		Until:              after.CreatedAt,
+		NewFeeds:           []SnapshotFeed{},
+		RemovedFeeds:       []SnapshotFeed{},
+		NewlyFollowed:      []SnapshotFeed{},
+		Unfollowed:         []SnapshotFeed{},
+		NewBlogrolls:       []SnapshotBlogroll{},
+		RemovedBlogrolls:   []SnapshotBlogroll{},
+		BlogrollChanges:    []BlogrollChange{},
+		NewRecommendations: []RecommendationChange{},
+		NewLinks:           []SnapshotLink{},
+		RemovedLinks:       []SnapshotLink{},
+		NewPosts:           []SnapshotPost{},
+	}
+	feedOf := func(url string) SnapshotFeed {
+		if feed, ok := after.Feeds[url]; ok {
+			return feed
+		}
+		return SnapshotFeed{Link: url}
+	}
+
+	for _, url := range sortedKeys(after.Feeds) {
+		feed := after.Feeds[url]
+		old, existed := before.Feeds[url]
+		if !existed {
+			diff.NewFeeds = append(diff.NewFeeds, feed)
+		} else if feed.Following && !old.Following {
+			diff.NewlyFollowed = append(diff.NewlyFollowed, feed)
+		} else if !feed.Following && old.Following {
+			diff.Unfollowed = append(diff.Unfollowed, feed)
+		}
+	}
+	for _, url := range sortedKeys(before.Feeds) {
+		if _, ok := after.Feeds[url]; !ok {
+			diff.RemovedFeeds = append(diff.RemovedFeeds, before.Feeds[url])
+		}
+	}
+
+	for _, url := range sortedKeys(after.Blogrolls) {
+		blogroll := after.Blogrolls[url]
+		old, existed := before.Blogrolls[url]
+		if !existed {
+			diff.NewBlogrolls = append(diff.NewBlogrolls, blogroll)
+			continue
+		}
+		change := BlogrollChange{
+			Link:    url,
+			Title:   blogroll.Title,
+			Added:   missingFrom(blogroll.Feeds, old.Feeds),
+			Removed: missingFrom(old.Feeds, blogroll.Feeds),
+		}
+		if len(change.Added) > 0 || len(change.Removed) > 0 {
+			diff.BlogrollChanges = append(diff.BlogrollChanges, change)
+		}
+	}
+	for _, url := range sortedKeys(before.Blogrolls) {
+		if _, ok := after.Blogrolls[url]; !ok {
+			diff.RemovedBlogrolls = append(diff.RemovedBlogrolls, before.Blogrolls[url])
+		}
+	}
+
+	for _, url := range sortedKeys(after.Recommendations) {
+		for _, recommended := range missingFrom(after.Recommendations[url], before.Recommendations[url]) {
+			diff.NewRecommendations = append(diff.NewRecommendations, RecommendationChange{
+				Feed:        feedOf(url),
+				Recommended: feedOf(recommended),
+			})
+		}
+	}
+
+	for _, id := range sortedKeys(after.Links) {
+		if _, ok := before.Links[id]; !ok {
+			diff.NewLinks = append(diff.NewLinks, after.Links[id])
+		}
+	}
+	for _, id := range sortedKeys(before.Links) {
+		if _, ok := after.Links[id]; !ok {
+			diff.RemovedLinks = append(diff.RemovedLinks, before.Links[id])
+		}
+	}
+
+	slices.SortFunc(diff.NewLinks, cmpSnapshotLink)
+	slices.SortFunc(diff.RemovedLinks, cmpSnapshotLink)
+
+	for _, id := range sortedKeys(after.Posts) {
+		if _, ok := before.Posts[id]; !ok {
+			diff.NewPosts = append(diff.NewPosts, after.Posts[id])
+		}
+	}
+	for id := range before.Posts {
+		if _, ok := after.Posts[id]; !ok {
+			diff.RemovedPosts++
+		}
+	}
+	return diff
+}
+
+func cmpSnapshotLink(a, b SnapshotLink) int {
+	if bySource := strings.Compare(a.Source, b.Source); bySource != 0 {
+		return bySource
+	}
+	return strings.Compare(a.Destination, b.Destination)
+}
+
+// Items of a that aren't in b, both sorted
+func missingFrom(a, b []string) []string {
+	missing := []string{}
+	for _, item := range a {
+		if _, found := slices.BinarySearch(b, item); !found {
+			missing = append(missing, item)
+		}
+	}
+	return missing
+}
+
+// Compare this run to the last one, then remember this run for next time
+func (c *Crawler) ReportChanges() {
+	if !c.Config.DiffReport {
+		return
+	}
+	after := c.Results.Snapshot()
+	before := readSnapshot(c.Config.DiffSnapshotFile)
+	if before == nil {
+		log.Printf("No snapshot from a previous run, the next run will report changes")
+	} else {
+		diff := diffSnapshots(before, after)
+		folder := c.Config.DiffReportFolder
+		err := os.MkdirAll(folder, os.FileMode(0755))
+		ohno(err)
+		log.Printf("Writing changes since %s to %s", before.CreatedAt.Format(time.RFC3339), folder)
+		writeJson(diff, filepath.Join(folder, "diff.json"))
+		err = os.WriteFile(filepath.Join(folder, "diff.md"), []byte(diff.Markdown()), os.FileMode(0644))
+		ohno(err)
+	}
+	writeJson(after, c.Config.DiffSnapshotFile)
+}
+
+func markdownLink(title, link string) string {
+	if title == "" {
+		title = link
+	}
+	title = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "\n", " ").Replace(title)
+	return fmt.Sprintf("[%s](<%s>)", title, link)
+}
+
+// A "what's new in the network" page
+func (d *NetworkDiff) Markdown() string {
+	out := strings.Builder{}
+	out.WriteString("# What's new in the network\n\n")
+	fmt.Fprintf(&out, "Changes between %s and %s.\n", d.Since.Format(time.DateOnly), d.Until.Format(time.DateOnly))
+
+	section := func(title string, count int) bool {
+		if count == 0 {
+			return false
+		}
+		fmt.Fprintf(&out, "\n## %s (%d)\n\n", title, count)
+		return true
+	}
+	feeds := func(title string, feeds []SnapshotFeed) {
+		if section(title, len(feeds)) {
+			for _, feed := range feeds {
+				fmt.Fprintf(&out, "- %s\n", markdownLink(feed.Title, feed.Link))
+			}
+		}
+	}
+	blogrolls := func(title string, blogrolls []SnapshotBlogroll) {
+		if section(title, len(blogrolls)) {
+			for _, blogroll := range blogrolls {
+				fmt.Fprintf(&out, "- %s, listing %d feeds\n", markdownLink(blogroll.Title, blogroll.Link), len(blogroll.Feeds))
+			}
+		}
+	}
+	links := func(title string, links []SnapshotLink) {
+		if section(title, len(links)) {
+			for _, link := range links {
+				fmt.Fprintf(&out, "- <%s> → <%s> (%s)\n", link.Source, link.Destination, link.LinkType)
+			}
+		}
+	}
+
+	if section("New recommendations from feeds you follow", len(d.NewRecommendations)) {
+		for _, change := range d.NewRecommendations {
+			fmt.Fprintf(&out, "- %s recommends %s\n",
+				markdownLink(change.Feed.Title, change.Feed.Link),
+				markdownLink(change.Recommended.Title, change.Recommended.Link))
+		}
+	}
+	feeds("New feeds", d.NewFeeds)
+	feeds("Removed feeds", d.RemovedFeeds)
+	feeds("Newly followed", d.NewlyFollowed)
+	feeds("No longer followed", d.Unfollowed)
+	blogrolls("New blogrolls", d.NewBlogrolls)
+	blogrolls("Removed blogrolls", d.RemovedBlogrolls)
+	if section("Blogroll changes", len(d.BlogrollChanges)) {
+		for _, change := range d.BlogrollChanges {
+			fmt.Fprintf(&out, "- %s\n", markdownLink(change.Title, change.Link))
+			for _, feed := range change.Added {
+				fmt.Fprintf(&out, "  - Added <%s>\n", feed)
+			}
+			for _, feed := range change.Removed {
+				fmt.Fprintf(&out, "  - Removed <%s>\n", feed)
+			}
+		}
+	}
+	links("New links", d.NewLinks)
+	links("Removed links", d.RemovedLinks)
+	if section("New posts", len(d.NewPosts)) {
+		for _, post := range d.NewPosts {
+			fmt.Fprintf(&out, "- %s\n", markdownLink(post.Title, post.Link))
+		}
+	}
+	if d.RemovedPosts > 0 {
+		fmt.Fprintf(&out, "\n%d posts are no longer listed.\n", d.RemovedPosts)
+	}
+	return out.String()
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	GRAPH_FORMAT_GRAPHML = "graphml"
+	GRAPH_FORMAT_GEXF    = "gexf"
+	GRAPH_FORMAT_DOT     = "dot"
+	GRAPH_FORMAT_JSON    = "json"
+)
+
+var GRAPH_FORMATS = []string{
+	GRAPH_FORMAT_GRAPHML,
+	GRAPH_FORMAT_GEXF,
+	GRAPH_FORMAT_DOT,
+	GRAPH_FORMAT_JSON,
+}
+
+const DEFAULT_GRAPH_EXPORT_FOLDER = "static/network"
+
+func parseGraphFormats(formats []string) []string {
+	for _, format := range formats {
+		if !slices.Contains(GRAPH_FORMATS, format) {
+			panicf("Unknown graph export format: %s", format)
+		}
+	}
+	return formats
+}
+
+type GraphNode struct {
+	Id            string  `json:"id"`
+	Type          string  `json:"type"`
+	Title         string  `json:"title"`
+	Following     bool    `json:"following"`
+	PostCount     int     `json:"postcount"`
+	AvgPostLen    int     `json:"avgpostlen"`
+	AvgPostPerDay float32 `json:"avgpostperday"`
+	Distance      int     `json:"distance"`
+	PageRank      float64 `json:"pagerank"`
+	Rank          int     `json:"rank"`
+	Community     string  `json:"community"`
+}
+
+type GraphEdge struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	LinkType string `json:"link_type"`
+}
+
+type Graph struct {
+	Nodes []*GraphNode `json:"nodes"`
+	Links []*GraphEdge `json:"links"`
+}
+
+// The recommendation network, as published
+func (r *CrawlResults) BuildGraph() *Graph {
+	nodes := map[string]*GraphNode{}
+	node := func(url string, nodeType NodeType) *GraphNode {
+		if found, ok := nodes[url]; ok {
+			return found
+		}
+		found := &GraphNode{
+			Id:       url,
+			Type:     nodeType.String(),
+			Distance: -1,
+		}
+		nodes[url] = found
+		return found
+	}
+
+	graph := &Graph{}
+	for _, id := range sortedKeys(r.Links) {
+		link := r.Links[id]
+		node(link.Params.SourceURL, link.Params.SourceType)
+		node(link.Params.DestinationURL, link.Params.DestinationType)
+		graph.Links = append(graph.Links, &GraphEdge{
+			Source:   link.Params.SourceURL,
+			Target:   link.Params.DestinationURL,
+			LinkType: link.Params.LinkType,
+		})
+	}
+	for _, url := range sortedKeys(r.Blogrolls) {
+		node(url, NODE_TYPE_BLOGROLL).Title = r.Blogrolls[url].Title
+	}
+	for _, url := range sortedKeys(r.Feeds) {
+		found := r.Feeds[url]
+		feed := node(url, NODE_TYPE_FEED)
+		feed.Type = NODE_TYPE_FEED.String()
+		feed.Title = found.Feed.Title
+		feed.Following = found.IsDirect
+		feed.PostCount = found.Feed.Params.PostCount
+		feed.AvgPostLen = found.Feed.Params.AvgPostLen
+		feed.AvgPostPerDay = found.Feed.Params.AvgPostPerDay
+		feed.Rank = found.Feed.Params.Rank
+	}
+	distances := r.seedDistances()
+	pageRanks := r.pageRank()
+	communities := r.louvain()
+	for _, url := range sortedKeys(nodes) {
+		if distance, ok := distances[url]; ok {
+			nodes[url].Distance = distance
+		}
+		nodes[url].PageRank = pageRanks[url]
+		if community, ok := communities[url]; ok {
+			nodes[url].Community = buildSafeId("", community)
+		} else {
+			nodes[url].Community = buildSafeId("", url)
+		}
+		graph.Nodes = append(graph.Nodes, nodes[url])
+	}
+	return graph
+}
+
+func (c *Crawler) ExportGraph() {
+	if len(c.Config.GraphExportFormats) == 0 {
+		return
+	}
+	graph := c.Results.BuildGraph()
+	folder := c.Config.GraphExportFolder
+	err := os.MkdirAll(folder, os.FileMode(0755))
+	ohno(err)
+
+	for _, format := range c.Config.GraphExportFormats {
+		var output []byte
+		switch format {
+		case GRAPH_FORMAT_GRAPHML:
+			output = graph.GraphML()
+		case GRAPH_FORMAT_GEXF:
+			output = graph.GEXF()
+		case GRAPH_FORMAT_DOT:
+			output = graph.DOT()
+		case GRAPH_FORMAT_JSON:
+			output, err = json.MarshalIndent(graph, "", "  ")
+			ohno(err)
+		}
+		path := filepath.Join(folder, "network."+format)
+		log.Printf("Writing %d nodes and %d edges to %s", len(graph.Nodes), len(graph.Links), path)
+		err = os.WriteFile(path, output, os.FileMode(0644))
+		ohno(err)
+	}
+}
+
+// Attributes shared by GraphML and GEXF, with GraphML types
+var GRAPH_NODE_ATTRS = []struct {
+	Name string
+	Type string
+}{
+	{"type", "string"},
+	{"title", "string"},
+	{"following", "boolean"},
+	{"postcount", "int"},
+	{"avgpostlen", "int"},
+	{"avgpostperday", "float"},
+	{"distance", "int"},
+	{"pagerank", "double"},
+	{"rank", "int"},
+	{"community", "string"},
+}
+
+// GEXF names some types differently
+var GEXF_ATTR_TYPES = map[string]string{
+	"int": "integer",
+}
+
+func gexfAttrType(graphmlType string) string {
+	if gexfType, ok := GEXF_ATTR_TYPES[graphmlType]; ok {
+		return gexfType
+	}
+	return graphmlType
+}
+
+func (n *GraphNode) attrValues() []string {
+	return []string{
+		n.Type,
+		n.Title,
+		strconv.FormatBool(n.Following),
+		strconv.Itoa(n.PostCount),
+		strconv.Itoa(n.AvgPostLen),
+		strconv.FormatFloat(float64(n.AvgPostPerDay), 'f', -1, 32),
+		strconv.Itoa(n.Distance),
+		strconv.FormatFloat(n.PageRank, 'g', -1, 64),
+		strconv.Itoa(n.Rank),
+		n.Community,
+	}
+}
+
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Value   string     `xml:",chardata"`
+	Inner   []xmlNode  `xml:",any"`
+}
+
+func xmlElement(name string, value string, attrs ...string) xmlNode {
+	el := xmlNode{XMLName: xml.Name{Local: name}, Value: value}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		el.Attrs = append(el.Attrs, xml.Attr{Name: xml.Name{Local: attrs[i]}, Value: attrs[i+1]})
+	}
+	return el
+}
+
+func marshalXml(root xmlNode) []byte {
+	output, err := xml.MarshalIndent(root, "", "  ")
+	ohno(err)
+	return append([]byte(xml.Header), append(output, '\n')...)
+}
+
+// See: http://graphml.graphdrawing.org/
+func (g *Graph) GraphML() []byte {
+	root := xmlElement("graphml", "", "xmlns", "http://graphml.graphdrawing.org/xmlns")
+	for i, attr := range GRAPH_NODE_ATTRS {
+		root.Inner = append(root.Inner, xmlElement("key", "",
+			"id", fmt.Sprintf("n%d", i), "for", "node", "attr.name", attr.Name, "attr.type", attr.Type))
+	}
+	root.Inner = append(root.Inner, xmlElement("key", "",
+		"id", "e0", "for", "edge", "attr.name", "link_type", "attr.type", "string"))
+
+	graph := xmlElement("graph", "", "id", "network", "edgedefault", "directed")
+	for _, node := range g.Nodes {
+		el := xmlElement("node", "", "id", node.Id)
+		for i, value := range node.attrValues() {
+			el.Inner = append(el.Inner, xmlElement("data", value, "key", fmt.Sprintf("n%d", i)))
+		}
+		graph.Inner = append(graph.Inner, el)
+	}
+	for _, edge := range g.Links {
+		el := xmlElement("edge", "", "source", edge.Source, "target", edge.Target)
+		el.Inner = append(el.Inner, xmlElement("data", edge.LinkType, "key", "e0"))
+		graph.Inner = append(graph.Inner, el)
+	}
+	root.Inner = append(root.Inner, graph)
+	return marshalXml(root)
+}
+
+// See: https://gexf.net/
+func (g *Graph) GEXF() []byte {
+	root := xmlElement("gexf", "", "xmlns", "http://gexf.net/1.3", "version", "1.3")
+	graph := xmlElement("graph", "", "defaultedgetype", "directed")
+
+	attributes := xmlElement("attributes", "", "class", "node")
+	for i, attr := range GRAPH_NODE_ATTRS {
+		attributes.Inner = append(attributes.Inner, xmlElement("attribute", "",
+			"id", strconv.Itoa(i), "title", attr.Name, "type", gexfAttrType(attr.Type)))
+	}
+	edgeAttributes := xmlElement("attributes", "", "class", "edge")
+	edgeAttributes.Inner = append(edgeAttributes.Inner, xmlElement("attribute", "",
+		"id", "0", "title", "link_type", "type", "string"))
+	graph.Inner = append(graph.Inner, attributes, edgeAttributes)
+
+	nodes := xmlElement("nodes", "")
+	for _, node := range g.Nodes {
+		label := node.Title
+		if label == "" {
+			label = node.Id
+		}
+		el := xmlElement("node", "", "id", node.Id, "label", label)
+		values := xmlElement("attvalues", "")
+		for i, value := range node.attrValues() {
+			values.Inner = append(values.Inner, xmlElement("attvalue", "", "for", strconv.Itoa(i), "value", value))
+		}
+		el.Inner = append(el.Inner, values)
+		nodes.Inner = append(nodes.Inner, el)
+	}
+	edges := xmlElement("edges", "")
+	for i, edge := range g.Links {
+		el := xmlElement("edge", "", "id", strconv.Itoa(i), "source", edge.Source, "target", edge.Target, "label", edge.LinkType)
+		values := xmlElement("attvalues", "")
+		values.Inner = append(values.Inner, xmlElement("attvalue", "", "for", "0", "value", edge.LinkType))
+		el.Inner = append(el.Inner, values)
+		edges.Inner = append(edges.Inner, el)
+	}
+	graph.Inner = append(graph.Inner, nodes, edges)
+	root.Inner = append(root.Inner, graph)
+	return marshalXml(root)
+}
+
+// See: https://graphviz.org/doc/info/lang.html
+func (g *Graph) DOT() []byte {
+	quote := func(s string) string {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, "\n", " ")
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	out := strings.Builder{}
+	out.WriteString("digraph network {\n")
+	for _, node := range g.Nodes {
+		label := node.Title
+		if label == "" {
+			label = node.Id
+		}
+		fmt.Fprintf(&out, "  %s [label=%s, type=%s, following=%t, postcount=%d, avgpostlen=%d, avgpostperday=%s, distance=%d, pagerank=%s, rank=%d, community=%s];\n",
+			quote(node.Id), quote(label), quote(node.Type), node.Following, node.PostCount, node.AvgPostLen,
+			strconv.FormatFloat(float64(node.AvgPostPerDay), 'f', -1, 32), node.Distance,
+			strconv.FormatFloat(node.PageRank, 'g', -1, 64), node.Rank, quote(node.Community))
+	}
+	for _, edge := range g.Links {
+		fmt.Fprintf(&out, "  %s -> %s [label=%s];\n", quote(edge.Source), quote(edge.Target), quote(edge.LinkType))
+	}
+	out.WriteString("}\n")
+	return []byte(out.String())
+}
@@ -11,6 +11,11 @@ type PostFrontmatter struct {
 	Description string     `yaml:"description"`
 	Title       string     `yaml:"title"`
 	Params      PostParams `yaml:"params"`
+
+	// The feed's ID for the post, for finding duplicates
+	guid string
+	// Which feeds the include rules keep the post for
+	inclusion Inclusion
 }
 
 type PostParams struct {
@@ -20,12 +25,20 @@ type PostParams struct {
 	Link       string   `yaml:"link"`
 	Categories []string `yaml:"categories"`
 	Language   string   `yaml:"language"`
+	// Language is the primary subtag of the full BCP 47 tag
+	LanguageTag string `yaml:"language_tag"`
+	Author      string `yaml:"author,omitempty"`
+	// Where the date came from, see DATE_SOURCE_*
+	DateSource string `yaml:"date_source"`
+	// Feed links of other feeds with this post
+	AlsoSeenIn []string `yaml:"also_seen_in"`
 }
 
 func NewPostFrontmatter(feed_url, guid, link string) *PostFrontmatter {
 	out := new(PostFrontmatter)
 	out.Params.Id = buildSafePostId(feed_url, guid)
 	out.Params.Link = link
+	out.guid = guid
 	return out
 }
 
@@ -33,10 +46,15 @@ func (f *PostFrontmatter) WithTitle(title string) {
 	f.Title = truncateText(title, 200)
 }
 
+func (f *PostFrontmatter) WithAuthor(author string) {
+	f.Params.Author = truncateText(author, 200)
+}
+
 func (f *PostFrontmatter) WithLanguage(lang string) {
-	lang, err := languageFromLanguageTag(lang)
+	tag, err := normalizeLanguageTag(lang)
 	if err == nil {
-		f.Params.Language = lang
+		f.Params.LanguageTag = tag
+		f.Params.Language, _ = languageFromLanguageTag(tag)
 	}
 }
 
@@ -58,6 +76,16 @@ func (f *PostFrontmatter) WithDate(date string) {
 	f.Date = date
 }
 
+func (f *PostFrontmatter) WithAlsoSeenIn(feedLink string) {
+	if !slices.Contains(f.Params.AlsoSeenIn, feedLink) {
+		f.Params.AlsoSeenIn = append(f.Params.AlsoSeenIn, feedLink)
+	}
+}
+
+func (f *PostFrontmatter) WithDateSource(source string) {
+	f.Params.DateSource = source
+}
+
 func (f *PostFrontmatter) WithContent(content string) {
 	f.Params.Content = truncateText(readable(content), 300)
 }
@@ -66,11 +94,22 @@ func (f *PostFrontmatter) WithFeedLink(feed_link string) {
 	f.Params.FeedId = buildSafeId("", feed_link)
 }
 
+func (f *PostFrontmatter) WithInclusion(inclusion Inclusion) {
+	f.inclusion = inclusion
+}
+
+func (f *PostFrontmatter) WithLink(link string) {
+	f.Params.Link = link
+}
+
 type FeedFrontmatter struct {
 	Date        string     `yaml:"date"`
 	Description string     `yaml:"description"`
 	Title       string     `yaml:"title"`
 	Params      FeedParams `yaml:"params"`
+
+	// Links of the items in the feed, even when posts aren't collected
+	itemLinks []string
 }
 
 type FeedParams struct {
@@ -83,9 +122,39 @@ type FeedParams struct {
 	FeedType      string   `yaml:"feedtype"`
 	Categories    []string `yaml:"categories"`
 	Language      string   `yaml:"language"`
+	LanguageTag   string   `yaml:"languagetag"`
 	PostCount     int      `yaml:"postcount"`
 	AvgPostLen    int      `yaml:"avgpostlen"`
 	AvgPostPerDay float32  `yaml:"avgpostperday"`
+	FuturePosts   int      `yaml:"futureposts"`
+	Hub           string   `yaml:"hub"`
+	SelfLink      string   `yaml:"self"`
+	Aliases       []string `yaml:"aliases"`
+	Distance      int      `yaml:"distance"`
+	InDegree      int      `yaml:"indegree"`
+	PageRank      float64  `yaml:"pagerank"`
+	RecommendedBy int      `yaml:"recommendedby"`
+	Rank          int      `yaml:"rank"`
+	Priority      int      `yaml:"priority"`
+	HidePosts     bool     `yaml:"hideposts"`
+	// The identity the feed's website belongs to, if verified
+	Identity string `yaml:"identity"`
+	// Feeds this feed recommends that recommend it back
+	MutualRecommendations []string `yaml:"mutualrecommendations"`
+	// The shortest path from a seed to this feed
+	FoundVia []ProvenanceStep `yaml:"foundvia"`
+	// The cluster of tightly connected feeds this feed belongs to
+	Community           string   `yaml:"community"`
+	CommunityCategories []string `yaml:"communitycategories"`
+}
+
+// One page on the path from a seed to a feed
+type ProvenanceStep struct {
+	Link  string `yaml:"link"`
+	Type  string `yaml:"type"`
+	Title string `yaml:"title"`
+	// How the previous page linked here, empty for the seed
+	LinkType string `yaml:"link_type"`
 }
 
 func NewFeedFrontmatter(feed_url string) *FeedFrontmatter {
@@ -111,6 +180,71 @@ func (f *FeedFrontmatter) WithLink(link string) {
 	f.Params.Link = link
 }
 
+func (f *FeedFrontmatter) WithHub(hub string) {
+	f.Params.Hub = hub
+}
+
+func (f *FeedFrontmatter) WithSelfLink(self string) {
+	f.Params.SelfLink = self
+}
+
+// Another URL for this feed
+func (f *FeedFrontmatter) WithAlias(link string) {
+	f.Params.Aliases = append(f.Params.Aliases, link)
+}
+
+// Number of links between one of our seeds and this feed
+func (f *FeedFrontmatter) WithDistance(distance int) {
+	f.Params.Distance = distance
+}
+
+func (f *FeedFrontmatter) WithFoundVia(path []ProvenanceStep) {
+	f.Params.FoundVia = path
+}
+
+func (f *FeedFrontmatter) WithRanking(inDegree int, pageRank float64, recommendedBy int) {
+	f.Params.InDegree = inDegree
+	f.Params.PageRank = pageRank
+	f.Params.RecommendedBy = recommendedBy
+}
+
+func (f *FeedFrontmatter) WithRank(rank int) {
+	f.Params.Rank = rank
+}
+
+func (f *FeedFrontmatter) WithIdentity(id string) {
+	f.Params.Identity = id
+}
+
+func (f *FeedFrontmatter) WithCommunity(id string, categories []string) {
+	f.Params.Community = id
+	f.Params.CommunityCategories = categories
+}
+
+func (f *FeedFrontmatter) WithMutualRecommendations(links []string) {
+	f.Params.MutualRecommendations = links
+}
+
+// Apply settings from feeds.yaml for this feed
+func (f *FeedFrontmatter) WithOverride(override FeedOverride) {
+	if override.Title != nil {
+		f.WithTitle(*override.Title)
+	}
+	if override.Description != nil {
+		f.WithDescription(*override.Description)
+	}
+	if override.Language != nil {
+		f.WithLanguage(*override.Language)
+	}
+	f.WithCategories(override.Categories)
+	f.Params.Priority = intDefault(override.Priority, 0)
+	f.Params.HidePosts = boolDefault(override.HidePosts, false)
+}
+
+func (f *FeedFrontmatter) WithItemLinks(links []string) {
+	f.itemLinks = links
+}
+
 func (f *FeedFrontmatter) WithFeedType(feedType string) {
 	f.Params.FeedType = feedType
 }
@@ -124,6 +258,8 @@ func (f *FeedFrontmatter) IsPodcast(isPodcast bool) {
 }
 
 func (f *FeedFrontmatter) WithCategories(cats []string) {
+	// Sorted in place, and overrides share their categories with the config
+	cats = slices.Clone(cats)
 	slices.Sort(cats)
 	for _, cat := range slices.Compact(cats) {
 		cat = strings.TrimSpace(cat)
@@ -134,9 +270,10 @@ func (f *FeedFrontmatter) WithCategories(cats []string) {
 }
 
 func (f *FeedFrontmatter) WithLanguage(language string) {
-	lang, err := languageFromLanguageTag(language)
+	tag, err := normalizeLanguageTag(language)
 	if err == nil {
-		f.Params.Language = lang
+		f.Params.LanguageTag = tag
+		f.Params.Language, _ = languageFromLanguageTag(tag)
 	}
 }
 
@@ -152,6 +289,10 @@ func (f *FeedFrontmatter) WithPostCount(count int) {
 	f.Params.PostCount = count
 }
 
+func (f *FeedFrontmatter) WithFuturePosts(count int) {
+	f.Params.FuturePosts = count
+}
+
 func (f *FeedFrontmatter) WithBlogRolls(links []string) {
 	f.Params.BlogRolls = links
 }
@@ -166,6 +307,8 @@ type LinkParams struct {
 	DestinationType NodeType `yaml:"destination_type"`
 	DestinationURL  string   `yaml:"destination_url"`
 	LinkType        string   `yaml:"link_type"`
+	// rel=me links that link back
+	Verified bool `yaml:"verified"`
 }
 
 func NewLinkFrontmatter(source_type NodeType, source_url string, destination_type NodeType, destination_url, link_type string) *LinkFrontmatter {
@@ -178,6 +321,10 @@ func NewLinkFrontmatter(source_type NodeType, source_url string, destination_typ
 	return out
 }
 
+func (f *LinkFrontmatter) IsVerified(verified bool) {
+	f.Params.Verified = verified
+}
+
 func buildLinkId(source, dest string) string {
 	return md5Hex(source + "\n" + dest)
 }
@@ -234,3 +381,33 @@ func (f *BlogrollOutline) WithHtmlUrl(url string) {
 func (f *BlogrollOutline) WithCategory(cat string) {
 	f.Category = cat
 }
+
+// One person, many sites, joined by verified rel=me links
+type IdentityFrontmatter struct {
+	Title  string         `yaml:"title"`
+	Params IdentityParams `yaml:"params"`
+}
+
+type IdentityParams struct {
+	Id    string   `yaml:"id"`
+	Sites []string `yaml:"sites"`
+	Feeds []string `yaml:"feeds"`
+}
+
+func NewIdentityFrontmatter(site string) *IdentityFrontmatter {
+	out := new(IdentityFrontmatter)
+	out.Params.Id = buildSafeId("", site)
+	return out
+}
+
+func (f *IdentityFrontmatter) WithTitle(title string) {
+	f.Title = truncateText(title, 200)
+}
+
+func (f *IdentityFrontmatter) WithSite(link string) {
+	f.Params.Sites = append(f.Params.Sites, link)
+}
+
+func (f *IdentityFrontmatter) WithFeed(feedLink string) {
+	f.Params.Feeds = append(f.Params.Feeds, feedLink)
+}
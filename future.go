@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/gocolly/colly/v2"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	FUTURE_POSTS_DROP  = "drop"
+	FUTURE_POSTS_CLAMP = "clamp"
+	FUTURE_POSTS_KEEP  = "keep"
+)
+
+var FUTURE_POSTS_POLICIES = []string{
+	FUTURE_POSTS_DROP,
+	FUTURE_POSTS_CLAMP,
+	FUTURE_POSTS_KEEP,
+}
+
+func parseFuturePostsPolicy(policy *string) string {
+	out := strDefault(policy, FUTURE_POSTS_DROP)
+	if !slices.Contains(FUTURE_POSTS_POLICIES, out) {
+		panicf("Unknown future_posts policy: %s", out)
+	}
+	return out
+}
+
+// When the feed was fetched, earlier than now if we reused a previous fetch
+func fetchedAt(r *colly.Request) time.Time {
+	fetched, ok := getTime(r.Ctx, CTX_FETCHED_AT)
+	if !ok {
+		return time.Now()
+	}
+	return fetched
+}
+
+// Apply the future_posts policy to a post
+// Returns if the post should be kept, and if it was dated in the future
+func (c *Crawler) checkFutureDate(r *colly.Request, post *PostFrontmatter) (bool, bool) {
+	date, err := ParseDate(post.Date)
+	if err != nil {
+		return true, false
+	}
+	fetched := fetchedAt(r)
+	if !date.After(fetched.Add(c.Config.FuturePostsTolerance)) {
+		return true, false
+	}
+	switch c.Config.FuturePosts {
+	case FUTURE_POSTS_DROP:
+		log.Printf("Dropping post dated in the future (%s): %s", post.Date, post.Params.Link)
+		return false, true
+	case FUTURE_POSTS_CLAMP:
+		log.Printf("Clamping post dated in the future (%s): %s", post.Date, post.Params.Link)
+		post.WithDate(fetched.Format(time.RFC3339))
+	}
+	return true, true
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"log"
+)
+
+// Record a URL from feed_urls, or found through a non-OPML blogroll
+func (c *Crawler) TrackSeed(url string) {
+	c.Results.lock.Lock()
+	defer c.Results.lock.Unlock()
+	c.Results.Seeds[url] = true
+}
+
+// Check if we follow a feed
+func (c *Crawler) IsFollowed(url string) bool {
+	c.Results.lock.Lock()
+	defer c.Results.lock.Unlock()
+	return c.Results.isFollowed(url)
+}
+
+// A feed is followed when it's one of our seeds, listed in one of our
+// seed blogrolls, or linked from a website listed in a seed blogroll
+func (r *CrawlResults) isFollowed(url string) bool {
+	if r.Seeds[url] {
+		return true
+	}
+	for _, link := range r.LinksTo[url] {
+		if r.Seeds[link.Params.SourceURL] {
+			return true
+		}
+		if link.Params.LinkType != LINK_TYPE_LINK_REL_ALT || link.Params.SourceType != NODE_TYPE_WEBSITE {
+			continue
+		}
+		for _, siteLink := range r.LinksTo[link.Params.SourceURL] {
+			if r.Seeds[siteLink.Params.SourceURL] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *CrawlResults) indexLinks() {
+	r.LinksTo = map[string][]*LinkFrontmatter{}
+	r.LinksFrom = map[string][]*LinkFrontmatter{}
+	for _, id := range sortedKeys(r.Links) {
+		link := r.Links[id]
+		r.LinksTo[link.Params.DestinationURL] = append(r.LinksTo[link.Params.DestinationURL], link)
+		r.LinksFrom[link.Params.SourceURL] = append(r.LinksFrom[link.Params.SourceURL], link)
+	}
+}
+
+// Number of links between a seed and each reachable node
+func (r *CrawlResults) seedDistances() map[string]int {
+	distances, _ := r.seedTree()
+	return distances
+}
+
+// Breadth first search from the seeds
+// Returns each reachable node's distance, and the link it was first reached by
+func (r *CrawlResults) seedTree() (map[string]int, map[string]*LinkFrontmatter) {
+	distances := map[string]int{}
+	parents := map[string]*LinkFrontmatter{}
+	frontier := []string{}
+	for _, seed := range sortedKeys(r.Seeds) {
+		distances[seed] = 0
+		frontier = append(frontier, seed)
+	}
+	for len(frontier) > 0 {
+		node := frontier[0]
+		frontier = frontier[1:]
+		for _, link := range r.LinksFrom[node] {
+			next := link.Params.DestinationURL
+			if _, seen := distances[next]; !seen {
+				distances[next] = distances[node] + 1
+				parents[next] = link
+				frontier = append(frontier, next)
+			}
+		}
+	}
+	return distances, parents
+}
+
+// Classify feeds as following or discovered using the link graph,
+// and record how far each feed is from our seeds, and how it was found
+func (c *Crawler) ClassifyFeeds() {
+	results := c.Results
+
+	// Seeds may have been merged into another URL
+	for seed := range results.Seeds {
+		if canonical, ok := results.Canonicals[seed]; ok {
+			results.Seeds[canonical] = true
+		}
+	}
+	results.indexLinks()
+
+	distances, parents := results.seedTree()
+	for feedLink, found := range results.Feeds {
+		found.IsDirect = results.isFollowed(feedLink)
+		distance, ok := distances[feedLink]
+		if !ok {
+			log.Printf("Feed isn't reachable from a seed: %s", feedLink)
+			distance = -1
+		}
+		found.Feed.WithDistance(distance)
+		found.Feed.WithFoundVia(results.provenance(feedLink, parents))
+	}
+}
@@ -11,7 +11,7 @@ import (
 func (c *Crawler) OnHTML(element *colly.HTMLElement) {
 	r := element.Request
 	page_url := r.URL.String()
-	page_type := r.Ctx.GetAny("target_type")
+	page_type, _ := targetTypeOf(r)
 	if page_type != NODE_TYPE_WEBSITE {
 		// This isn't supposed to be a website
 		// Maybe we're seeing an HTML error for RSS feed?
@@ -61,7 +61,7 @@ func (c *Crawler) OnHTML(element *colly.HTMLElement) {
 // <link rel="blogroll" type="text/xml" href="https://feedland.com/opml?screenname=davewiner&catname=blogroll">
 func (c *Crawler) OnHTML_Link(element *goquery.Selection, r *colly.Request, isNofollow bool) {
 	page_url := r.URL.String()
-	page_type := r.Ctx.GetAny("target_type")
+	page_type, _ := targetTypeOf(r)
 	if page_type != NODE_TYPE_WEBSITE {
 		// This isn't supposed to be a website
 		// Maybe we're seeing an HTML error for RSS feed?
@@ -75,7 +75,6 @@ func (c *Crawler) OnHTML_Link(element *goquery.Selection, r *colly.Request, isNo
 		return
 	}
 
-	// TODO: track canonical links
 	href = r.AbsoluteURL(href)
 
 	if !isNofollow {
@@ -88,9 +87,11 @@ func (c *Crawler) OnHTML_Link(element *goquery.Selection, r *colly.Request, isNo
 			log.Printf("Feed from HTML: %s", href)
 			c.Request(NODE_TYPE_WEBSITE, page_url, NODE_TYPE_FEED, href, LINK_TYPE_LINK_REL_ALT, r.Depth+1)
 		}
-		if slices.Contains(rels, "canonical") {
+		if slices.Contains(rels, "canonical") && href != "" && href != page_url {
+			// Same page, so there's no need to crawl it again
+			// Duplicates are merged after the crawl
 			log.Printf("canonical URL: %s", href)
-			c.Request(NODE_TYPE_WEBSITE, page_url, NODE_TYPE_CANONICAL, href, LINK_TYPE_LINK_REL_CANONICAL, r.Depth+1)
+			c.SaveLink(NewLinkFrontmatter(NODE_TYPE_WEBSITE, page_url, NODE_TYPE_CANONICAL, href, LINK_TYPE_LINK_REL_CANONICAL))
 		}
 	}
 
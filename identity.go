@@ -0,0 +1,134 @@
+package main
+
+import (
+	"log"
+	"slices"
+)
+
+// Verify rel=me links, then group sites into identities
+//
+// A rel=me link is verified when the page it points at links back with
+// rel=me. Sites joined by verified links belong to the same person.
+// See: https://microformats.org/wiki/rel-me
+func (c *Crawler) VerifyIdentities() {
+	results := c.Results
+
+	// Site -> sites it claims with rel=me
+	claims := map[string]map[string]bool{}
+	for _, link := range results.Links {
+		if link.Params.LinkType != LINK_TYPE_LINK_REL_ME {
+			continue
+		}
+		source := siteKey(link.Params.SourceURL)
+		if claims[source] == nil {
+			claims[source] = map[string]bool{}
+		}
+		claims[source][siteKey(link.Params.DestinationURL)] = true
+	}
+
+	sets := unionFind{}
+	// Site -> URL, for the identity pages
+	sites := map[string]string{}
+	for _, id := range sortedKeys(results.Links) {
+		link := results.Links[id]
+		if link.Params.LinkType != LINK_TYPE_LINK_REL_ME {
+			continue
+		}
+		source := siteKey(link.Params.SourceURL)
+		destination := siteKey(link.Params.DestinationURL)
+		if !claims[destination][source] {
+			continue
+		}
+		link.IsVerified(true)
+		sets.union(source, destination)
+		if _, ok := sites[source]; !ok {
+			sites[source] = link.Params.SourceURL
+		}
+		if _, ok := sites[destination]; !ok {
+			sites[destination] = link.Params.DestinationURL
+		}
+	}
+
+	identities := map[string]*IdentityFrontmatter{}
+	for _, key := range sortedKeys(sites) {
+		root := sets.find(key)
+		identity, ok := identities[root]
+		if !ok {
+			// Sites are sorted, so the first site names the identity
+			identity = NewIdentityFrontmatter(sites[key])
+			identity.WithTitle(sites[key])
+			identities[root] = identity
+		}
+		identity.WithSite(sites[key])
+	}
+
+	titled := map[string]bool{}
+	for _, feedLink := range sortedKeys(results.Feeds) {
+		feed := results.Feeds[feedLink].Feed
+		root, ok := c.identityOf(feed, sites, sets)
+		if !ok {
+			continue
+		}
+		identity := identities[root]
+		identity.WithFeed(feedLink)
+		feed.WithIdentity(identity.Params.Id)
+		if !titled[root] && feed.Title != "" {
+			identity.WithTitle(feed.Title)
+			titled[root] = true
+		}
+	}
+
+	for _, identity := range identities {
+		log.Printf("Identity %s has %d sites and %d feeds", identity.Title, len(identity.Params.Sites), len(identity.Params.Feeds))
+		results.Identities[identity.Params.Id] = identity
+	}
+
+	c.findMutualRecommendations()
+}
+
+// The identity of the website a feed belongs to
+func (c *Crawler) identityOf(feed *FeedFrontmatter, sites map[string]string, sets unionFind) (string, bool) {
+	websites := []string{}
+	if feed.Params.Link != "" {
+		websites = append(websites, feed.Params.Link)
+	}
+	// Websites that link to the feed as their own
+	for _, link := range c.Results.LinksTo[feed.Params.FeedLink] {
+		if link.Params.LinkType == LINK_TYPE_LINK_REL_ALT {
+			websites = append(websites, link.Params.SourceURL)
+		}
+	}
+	for _, website := range websites {
+		key := siteKey(website)
+		if _, ok := sites[key]; ok {
+			return sets.find(key), true
+		}
+	}
+	return "", false
+}
+
+// Feeds that recommend each other, like two blogs in each other's blogroll
+func (c *Crawler) findMutualRecommendations() {
+	results := c.Results
+	recommends := map[string]map[string]bool{}
+	for feedLink := range results.Feeds {
+		recommends[feedLink] = map[string]bool{}
+		for _, recommended := range results.recommendedFrom(feedLink) {
+			recommends[feedLink][recommended] = true
+		}
+	}
+	for _, feedLink := range sortedKeys(results.Feeds) {
+		mutual := []string{}
+		for other := range recommends[feedLink] {
+			if recommends[other][feedLink] {
+				mutual = append(mutual, other)
+			}
+		}
+		if len(mutual) == 0 {
+			continue
+		}
+		slices.Sort(mutual)
+		log.Printf("Feed %s and %d others recommend each other", feedLink, len(mutual))
+		results.Feeds[feedLink].Feed.WithMutualRecommendations(mutual)
+	}
+}
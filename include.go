@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+)
+
+// Allow-list for topic focused news feeds
+// A post is kept only if it matches one of the rules or categories,
+// and is written in one of the languages
+type IncludeRules struct {
+	Rules      []BlockRule `yaml:"rules"`
+	Categories []string    `yaml:"categories"`
+	Languages  []string    `yaml:"languages"`
+}
+
+type CompiledIncludeRules struct {
+	rules      []*CompiledBlockRule
+	categories []string
+	languages  []string
+}
+
+func (i *IncludeRules) Compile() *CompiledIncludeRules {
+	if i == nil {
+		// Include everything
+		return &CompiledIncludeRules{}
+	}
+	out := &CompiledIncludeRules{
+		rules: compileBlockRules(i.Rules, false),
+	}
+	for _, category := range i.Categories {
+		out.categories = append(out.categories, strings.ToLower(strings.TrimSpace(category)))
+	}
+	for _, language := range i.Languages {
+		language, err := languageFromLanguageTag(language)
+		if err != nil {
+			panicf("Invalid include language: %v", err)
+		}
+		out.languages = append(out.languages, language)
+	}
+	return out
+}
+
+// Returns why the post wasn't included
+func (i *CompiledIncludeRules) Excludes(b *Blockable, language string) (bool, string) {
+	if len(i.languages) > 0 && !slices.Contains(i.languages, language) {
+		return true, "language"
+	}
+	if len(i.rules) == 0 && len(i.categories) == 0 {
+		return false, ""
+	}
+	for _, rule := range i.rules {
+		if matched, _ := rule.Matches(b); matched {
+			return false, ""
+		}
+	}
+	for _, category := range b.Categories {
+		if slices.Contains(i.categories, strings.ToLower(strings.TrimSpace(category))) {
+			return false, ""
+		}
+	}
+	return true, "topic"
+}
+
+// Which feeds a post is kept for
+// Whether a feed is followed is only known once the crawl completes
+type Inclusion int
+
+const (
+	INCLUDE_ALWAYS Inclusion = iota
+	INCLUDE_IF_FOLLOWED
+	INCLUDE_IF_DISCOVERED
+	INCLUDE_NEVER
+)
+
+// Check a post against the include rules for followed and discovered feeds
+func (c *Crawler) inclusionOf(link string, b *Blockable, language string) Inclusion {
+	following, reason := c.Config.IncludeFollowing.Excludes(b, language)
+	discovered, _ := c.Config.IncludeDiscovered.Excludes(b, language)
+	switch {
+	case following && discovered:
+		log.Printf("Post doesn't match the included %s: %s", reason, link)
+		return INCLUDE_NEVER
+	case following:
+		return INCLUDE_IF_DISCOVERED
+	case discovered:
+		return INCLUDE_IF_FOLLOWED
+	}
+	return INCLUDE_ALWAYS
+}
+
+// Drop posts the include rules don't keep for their feed
+// Runs after ClassifyFeeds, once we know which feeds are followed
+func (c *Crawler) ApplyIncludeRules() {
+	results := c.Results
+	followed := map[string]bool{}
+	for _, found := range results.Feeds {
+		followed[found.Feed.Params.Id] = found.IsDirect
+	}
+	for _, id := range sortedKeys(results.Posts) {
+		post := results.Posts[id]
+		isDirect := followed[post.Params.FeedId]
+		if (post.inclusion == INCLUDE_IF_FOLLOWED && !isDirect) || (post.inclusion == INCLUDE_IF_DISCOVERED && isDirect) {
+			log.Printf("Post doesn't match the include rules for its feed: %s", post.Params.Link)
+			delete(results.Posts, id)
+		}
+	}
+}
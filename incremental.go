@@ -0,0 +1,139 @@
+package main
+
+import (
+	"github.com/antchfx/xmlquery"
+	"github.com/gocolly/colly/v2"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Publisher hints about how often a feed should be refetched
+type RefreshHints struct {
+	// How long the previous fetch remains fresh, zero means always refetch
+	Refresh   time.Duration
+	SkipHours []int
+	SkipDays  []string
+}
+
+// See: https://www.rssboard.org/rss-specification#optionalChannelElements
+// See: https://web.resource.org/rss/1.0/modules/syndication/
+func (c *Crawler) parseRssRefreshHints(channel *xmlquery.Node) RefreshHints {
+	hints := RefreshHints{}
+
+	// ttl is a number of minutes
+	ttl, err := strconv.Atoi(xmlText(channel, "ttl"))
+	if err == nil && ttl > 0 {
+		hints.Refresh = time.Duration(ttl) * time.Minute
+	}
+
+	period := xmlquery.QuerySelector(channel, c.SyUpdatePeriodWithNamespaceXPath)
+	if period != nil {
+		frequency := 1
+		frequencyNode := xmlquery.QuerySelector(channel, c.SyUpdateFrequencyWithNamespaceXPath)
+		if frequencyNode != nil {
+			parsed, err := strconv.Atoi(strings.TrimSpace(frequencyNode.InnerText()))
+			if err == nil && parsed > 0 {
+				frequency = parsed
+			}
+		}
+		syRefresh := syndicationPeriod(period.InnerText()) / time.Duration(frequency)
+		// Respect whichever hint asks for the longer wait
+		hints.Refresh = max(hints.Refresh, syRefresh)
+	}
+
+	for _, hour := range xmlTextMultiple(channel, "skipHours/hour") {
+		parsed, err := strconv.Atoi(hour)
+		if err == nil && parsed >= 0 && parsed <= 23 {
+			hints.SkipHours = append(hints.SkipHours, parsed)
+		}
+	}
+	for _, day := range xmlTextMultiple(channel, "skipDays/day") {
+		if len(day) > 0 {
+			hints.SkipDays = append(hints.SkipDays, day)
+		}
+	}
+	return hints
+}
+
+func syndicationPeriod(period string) time.Duration {
+	switch strings.ToLower(strings.TrimSpace(period)) {
+	case "hourly":
+		return time.Hour
+	case "weekly":
+		return 7 * 24 * time.Hour
+	case "monthly":
+		return 30 * 24 * time.Hour
+	case "yearly":
+		return 365 * 24 * time.Hour
+	default:
+		// daily is the default
+		return 24 * time.Hour
+	}
+}
+
+// Check if the feed should be fetched now, given when it was last fetched
+func (h *RefreshHints) IsDue(fetchedAt, now time.Time) bool {
+	now = now.UTC()
+	// skipHours are in GMT
+	if slices.Contains(h.SkipHours, now.Hour()) {
+		return false
+	}
+	for _, day := range h.SkipDays {
+		if strings.EqualFold(day, now.Weekday().String()) {
+			return false
+		}
+	}
+	return !now.Before(fetchedAt.Add(h.Refresh))
+}
+
+// Remember the feed response so the next incremental crawl can reuse it
+func (c *Crawler) TrackFetch(resp *colly.Response, doc *xmlquery.Node) {
+	channel := xmlquery.FindOne(doc, "/rss/channel")
+	if channel == nil {
+		// Only RSS offers refresh hints
+		return
+	}
+	robotsTag := ""
+	lastModified := ""
+	if resp.Headers != nil {
+		robotsTag = strings.Join(resp.Headers.Values("X-Robots-Tag"), ", ")
+		lastModified = resp.Headers.Get("Last-Modified")
+	}
+	c.db.TrackFetch(resp.Request.URL.String(), time.Now(), c.parseRssRefreshHints(channel), robotsTag, lastModified, resp.Body)
+}
+
+// In incremental mode, reuse the previous response of feeds that aren't due yet
+func (c *Crawler) OnIncrementalRequest(r *colly.Request) {
+	feed_url := r.URL.String()
+	fetch, found := c.db.GetFetch(feed_url)
+	if !found {
+		return
+	}
+	hints := fetch.RefreshHints()
+	if hints.IsDue(fetch.FetchedAt, time.Now()) {
+		return
+	}
+
+	log.Printf("Reusing previous fetch of: %s", feed_url)
+	r.Abort()
+	putTime(r.Ctx, CTX_FETCHED_AT, fetch.FetchedAt)
+
+	headers := http.Header{}
+	if fetch.RobotsTag != "" {
+		headers.Set("X-Robots-Tag", fetch.RobotsTag)
+	}
+	if fetch.LastModified != "" {
+		headers.Set("Last-Modified", fetch.LastModified)
+	}
+	c.handleResponse(&colly.Response{
+		StatusCode: 200,
+		Body:       fetch.Body,
+		Ctx:        r.Ctx,
+		Request:    r,
+		Headers:    &headers,
+	}, true)
+}
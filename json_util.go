@@ -1,31 +1,75 @@
-
 package main
 
 import (
-	"github.com/itchyny/gojq"
 	"encoding/json"
 	"fmt"
-	"net/http"
-	"io"
+	"github.com/gocolly/colly/v2"
+	"github.com/itchyny/gojq"
+	"log"
 )
 
-func jqProcessUrl(url string, query string) []string {
-	jq, err := gojq.Parse(query)
-	ohno(err)
+const NON_OPML_HANDLER_JQ = "jq"
 
-	res, err := http.Get(url)
-	ohno(err)
+// Fetch the non-OPML blogroll sources and return the feed URLs they list
+//
+// Sources are fetched with a clone of the crawler's collector, so they
+// share its transport, proxy, user agent, robots.txt handling and cache.
+// Failures are reported per-source and never abort the crawl.
+func (c *Crawler) CollectNonOpmlBlogrolls() []string {
+	results := []string{}
+	if len(c.Config.NonOpmlBlogroll) == 0 {
+		return results
+	}
+
+	collector := c.Collector.Clone()
+	collector.OnRequest(OnRequestHandler)
+	collector.OnError(func(resp *colly.Response, err error) {
+		source := resp.Request.URL.String()
+		c.SaveSourceError(source, resp.Ctx.Get(CTX_HANDLER), fmt.Errorf("%v %v", resp.StatusCode, err))
+	})
+	collector.OnResponse(func(resp *colly.Response) {
+		source := resp.Request.URL.String()
+		handler := resp.Ctx.Get(CTX_HANDLER)
+		found, err := jqProcess(resp.Body, resp.Ctx.Get(CTX_SETTINGS))
+		if err != nil {
+			c.SaveSourceError(source, handler, err)
+			return
+		}
+		log.Printf("Found %d feeds in %s", len(found), source)
+		results = append(results, found...)
+	})
 
-	body, err := io.ReadAll(res.Body)
-	ohno(err)
-	res.Body.Close()
-	if res.StatusCode > 300 {
-		ohno(fmt.Errorf("Unexpected status code: %v", res.StatusCode))
+	for _, source := range c.Config.NonOpmlBlogroll {
+		if source.Handler != NON_OPML_HANDLER_JQ {
+			c.SaveSourceError(source.Url, source.Handler, fmt.Errorf("Unknown handler: %s", source.Handler))
+			continue
+		}
+		ctx := colly.NewContext()
+		ctx.Put(CTX_HANDLER, source.Handler)
+		ctx.Put(CTX_SETTINGS, source.Settings)
+		err := collector.Request("GET", source.Url, nil, ctx, nil)
+		if err != nil {
+			// Request errors (robots.txt, invalid URL) skip the callbacks
+			c.SaveSourceError(source.Url, source.Handler, err)
+		}
+	}
+	collector.Wait()
+	return results
+}
+
+// Run a jq query over a JSON document, the query must produce strings
+func jqProcess(body []byte, query string) ([]string, error) {
+	jq, err := gojq.Parse(query)
+	if err != nil {
+		return nil, err
 	}
 
-	var decoded_json map[string]any
+	// The root may be an object, array or scalar
+	var decoded_json any
 	err = json.Unmarshal(body, &decoded_json)
-	ohno(err)
+	if err != nil {
+		return nil, err
+	}
 
 	iter := jq.Run(decoded_json)
 	results := []string{}
@@ -38,14 +82,13 @@ func jqProcessUrl(url string, query string) []string {
 			if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
 				break
 			}
-			ohno(err)
+			return nil, err
 		}
 		s, ok := v.(string)
 		if !ok {
-			ohno(fmt.Errorf("Unexpected type %v", v))
+			return nil, fmt.Errorf("Unexpected type %v", v)
 		}
-		fmt.Println(s)
 		results = append(results, s)
 	}
-	return results
+	return results, nil
 }
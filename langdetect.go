@@ -0,0 +1,148 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"unicode"
+)
+
+// Common words, enough to tell Latin script languages apart
+var LANGUAGE_STOPWORDS = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "on", "this", "you", "are", "was", "be", "as", "have", "not", "but"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "sich", "auf", "für", "ich", "es", "von", "dem", "auch", "wir"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "un", "du", "que", "pour", "dans", "pas", "qui", "sur", "au", "avec", "ce", "nous", "il"},
+	"es": {"el", "la", "los", "las", "y", "que", "de", "en", "es", "un", "una", "por", "para", "con", "no", "se", "del", "al", "lo", "como"},
+	"it": {"il", "di", "che", "e", "la", "un", "una", "per", "non", "è", "sono", "del", "della", "con", "gli", "le", "ma", "anche", "come", "questo"},
+	"pt": {"o", "a", "os", "as", "e", "que", "de", "do", "da", "em", "um", "uma", "para", "com", "não", "é", "se", "no", "na", "por"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "met", "voor", "ik", "je", "die", "er", "maar", "ook", "wat"},
+	"sv": {"och", "att", "det", "som", "en", "är", "på", "för", "med", "av", "till", "inte", "den", "jag", "har", "om", "ett", "de", "var", "så"},
+}
+
+// Scripts used by a single common language
+var LANGUAGE_SCRIPTS = []struct {
+	Language string
+	Script   *unicode.RangeTable
+}{
+	{"el", unicode.Greek},
+	{"he", unicode.Hebrew},
+	{"ar", unicode.Arabic},
+	{"ko", unicode.Hangul},
+	{"th", unicode.Thai},
+	{"hi", unicode.Devanagari},
+	{"hy", unicode.Armenian},
+	{"ka", unicode.Georgian},
+}
+
+// Guess the language of some text, without a network service
+// Returns an empty string when unsure
+func detectLanguage(text string) string {
+	letters := 0
+	scripts := map[string]int{}
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			scripts["latin"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["cyrillic"]++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			scripts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["han"]++
+		default:
+			for _, s := range LANGUAGE_SCRIPTS {
+				if unicode.Is(s.Script, r) {
+					scripts[s.Language]++
+					break
+				}
+			}
+		}
+	}
+	if letters < 10 {
+		return ""
+	}
+
+	// Most of the letters should come from one script
+	dominant := ""
+	for script, count := range scripts {
+		if count*2 > letters {
+			dominant = script
+		}
+	}
+	switch dominant {
+	case "":
+		if scripts["kana"] > 0 {
+			// Japanese mixes kana and kanji
+			return "ja"
+		}
+		return ""
+	case "latin":
+		return detectLatinLanguage(text)
+	case "cyrillic":
+		if strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+			return "uk"
+		}
+		return "ru"
+	case "kana":
+		return "ja"
+	case "han":
+		if scripts["kana"] > 0 {
+			return "ja"
+		}
+		return "zh"
+	}
+	return dominant
+}
+
+func detectLatinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	scores := map[string]int{}
+	for _, word := range words {
+		for language, stopwords := range LANGUAGE_STOPWORDS {
+			for _, stopword := range stopwords {
+				if word == stopword {
+					scores[language]++
+					break
+				}
+			}
+		}
+	}
+
+	best, bestScore, runnerUpScore := "", 0, 0
+	for language, score := range scores {
+		if score > bestScore || (score == bestScore && language < best) {
+			runnerUpScore = max(runnerUpScore, bestScore)
+			best, bestScore = language, score
+		} else if score > runnerUpScore {
+			runnerUpScore = score
+		}
+	}
+	// Short or mixed text isn't enough to go on
+	if bestScore < 2 || bestScore == runnerUpScore {
+		return ""
+	}
+	return best
+}
+
+// Posts already hold readable text
+func detectPostLanguage(post *PostFrontmatter) string {
+	return detectLanguage(strings.Join([]string{post.Title, post.Description, post.Params.Content}, "\n"))
+}
+
+// Check a post against the languages allow-list
+// Posts in an unknown language are kept
+func (c *Crawler) isAllowedLanguage(link, tag string) bool {
+	if len(c.Config.Languages) == 0 || tag == "" {
+		return true
+	}
+	if !languageMatches(tag, c.Config.Languages) {
+		log.Printf("Language %s isn't allowed: %s", tag, link)
+		return false
+	}
+	return true
+}
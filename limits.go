@@ -0,0 +1,97 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"slices"
+)
+
+// Keep the max_recommendations highest ranked discovered feeds
+func (c *Crawler) limitRecommendations() {
+	results := c.Results
+	discovered := []*FeedResult{}
+	for _, found := range results.Feeds {
+		if !found.IsDirect {
+			discovered = append(discovered, found)
+		}
+	}
+	if len(discovered) <= c.Config.MaxRecommendations {
+		return
+	}
+
+	slices.SortFunc(discovered, func(a, b *FeedResult) int {
+		return cmp.Compare(a.Feed.Params.Rank, b.Feed.Params.Rank)
+	})
+	log.Printf("Dropping %d recommendations over the limit", len(discovered)-c.Config.MaxRecommendations)
+	for _, found := range discovered[c.Config.MaxRecommendations:] {
+		delete(results.Feeds, found.Feed.Params.FeedLink)
+	}
+}
+
+// Apply the post age limit, then pick the newest posts fairly across feeds
+func (c *Crawler) limitPosts() {
+	results := c.Results
+	// Feed ID -> max posts
+	maxPostsByFeed := map[string]int{}
+	for _, found := range results.Feeds {
+		override := c.Config.OverrideFor(found.Feed.Params.FeedLink, found.Feed.Params.Link)
+		maxPostsByFeed[found.Feed.Params.Id] = intDefault(override.MaxPostsPerFeed, c.Config.MaxPostsPerFeed)
+	}
+
+	byFeed := map[string][]*PostFrontmatter{}
+	for _, post := range results.Posts {
+		if _, ok := maxPostsByFeed[post.Params.FeedId]; !ok {
+			// The feed was dropped
+			continue
+		}
+		date, err := ParseDate(post.Date)
+		if err == nil && date.Before(c.Config.PostAgeLimit) {
+			continue
+		}
+		byFeed[post.Params.FeedId] = append(byFeed[post.Params.FeedId], post)
+	}
+
+	for feedId, posts := range byFeed {
+		slices.SortFunc(posts, cmpPostsNewestFirst)
+		if len(posts) > maxPostsByFeed[feedId] {
+			posts = posts[:max(maxPostsByFeed[feedId], 0)]
+		}
+		byFeed[feedId] = posts
+	}
+
+	// Round robin, each feed's newest post, then each feed's second newest...
+	// so frequent posters can't crowd out everyone else
+	selected := map[string]*PostFrontmatter{}
+	for rank := 0; len(selected) < c.Config.MaxPosts; rank++ {
+		round := []*PostFrontmatter{}
+		for _, posts := range byFeed {
+			if rank < len(posts) {
+				round = append(round, posts[rank])
+			}
+		}
+		if len(round) == 0 {
+			break
+		}
+		slices.SortFunc(round, cmpPostsNewestFirst)
+		for _, post := range round {
+			if len(selected) >= c.Config.MaxPosts {
+				break
+			}
+			selected[post.Params.Id] = post
+		}
+	}
+
+	if len(selected) < len(results.Posts) {
+		log.Printf("Keeping %d of %d posts", len(selected), len(results.Posts))
+	}
+	results.Posts = selected
+}
+
+func cmpPostsNewestFirst(a, b *PostFrontmatter) int {
+	// Reverse chronological, with a stable tie-break
+	byDate := cmpDateStr(b.Date, a.Date)
+	if byDate != 0 {
+		return byDate
+	}
+	return cmp.Compare(a.Params.Id, b.Params.Id)
+}
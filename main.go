@@ -5,5 +5,7 @@ func main() {
 	cleanupContentOutputDirs(config)
 	crawler := NewCrawler(config)
 	crawler.Crawl(config.FeedUrls...)
+	crawler.ListenWebSub()
+	crawler.Publish()
 	crawler.PurgeNoIndex()
 }
@@ -18,7 +18,10 @@ func (c *Crawler) OnXML_Opml(_ *http.Header, r *colly.Request, opml *xmlquery.No
 
 	xmlOutlines := xmlquery.Find(opml, "body//outline")
 	for _, xmlOutline := range xmlOutlines {
-		outline, ok := c.OnXML_OpmlOutline(r, xmlOutline)
+		// Only follow the first recommendations of each blogroll
+		// Our own seed blogrolls are followed in full
+		follow := isSeedRequest(r) || len(blogroll.Params.Outlines) < c.Config.MaxRecommendationsPerFeed
+		outline, ok := c.OnXML_OpmlOutline(r, xmlOutline, follow)
 		if ok {
 			blogroll.Params.Outlines = append(blogroll.Params.Outlines, outline)
 		}
@@ -29,7 +32,7 @@ func (c *Crawler) OnXML_Opml(_ *http.Header, r *colly.Request, opml *xmlquery.No
 	}
 }
 
-func (c *Crawler) OnXML_OpmlOutline(r *colly.Request, outline *xmlquery.Node) (BlogrollOutline, bool) {
+func (c *Crawler) OnXML_OpmlOutline(r *colly.Request, outline *xmlquery.Node, follow bool) (BlogrollOutline, bool) {
 	blogroll_url := r.URL.String()
 	out := BlogrollOutline{}
 
@@ -54,11 +57,15 @@ func (c *Crawler) OnXML_OpmlOutline(r *colly.Request, outline *xmlquery.Node) (B
 		// If it parses as RSS or Atom, handle it as such
 		if feedUrl != "" {
 			feedUrl = r.AbsoluteURL(feedUrl)
-			c.Request(NODE_TYPE_BLOGROLL, blogroll_url, NODE_TYPE_FEED, feedUrl, LINK_TYPE_FROM_OPML, r.Depth+1)
+			if follow {
+				c.Request(NODE_TYPE_BLOGROLL, blogroll_url, NODE_TYPE_FEED, feedUrl, LINK_TYPE_FROM_OPML, r.Depth+1)
+			}
 		}
 		if webUrl != "" {
 			webUrl = r.AbsoluteURL(webUrl)
-			c.Request(NODE_TYPE_BLOGROLL, blogroll_url, NODE_TYPE_WEBSITE, webUrl, LINK_TYPE_FROM_OPML, r.Depth+1)
+			if follow {
+				c.Request(NODE_TYPE_BLOGROLL, blogroll_url, NODE_TYPE_WEBSITE, webUrl, LINK_TYPE_FROM_OPML, r.Depth+1)
+			}
 		}
 	}
 
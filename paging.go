@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/antchfx/xmlquery"
+	"github.com/gocolly/colly/v2"
+	"log"
+)
+
+// RFC 5005 link relations, in order of preference
+// See: https://www.rfc-editor.org/rfc/rfc5005
+var PAGING_LINK_RELS = []string{
+	"next",
+	"prev-archive",
+}
+
+// The URL of the feed a request belongs to
+// Backfill pages are attributed to the feed they were found from
+func feedUrlOf(r *colly.Request) string {
+	backfillOf := r.Ctx.Get(CTX_BACKFILL_OF)
+	if backfillOf != "" {
+		return backfillOf
+	}
+	selfUrl := r.Ctx.Get(CTX_SELF_URL)
+	if selfUrl != "" {
+		return selfUrl
+	}
+	return r.URL.String()
+}
+
+// The URL a feed was requested by, which is what blogrolls link to
+// Use this rather than the feed URL to check if a feed is followed
+func requestedFeedUrlOf(r *colly.Request) string {
+	origin := r.Ctx.Get(CTX_BACKFILL_FROM)
+	if origin != "" {
+		return origin
+	}
+	return r.URL.String()
+}
+
+func isBackfillPage(r *colly.Request) bool {
+	return r.Ctx.Get(CTX_BACKFILL_OF) != ""
+}
+
+func backfillPageOf(r *colly.Request) int {
+	page, _ := getInt(r.Ctx, CTX_BACKFILL_PAGE)
+	return page
+}
+
+func (c *Crawler) findNextPage(r *colly.Request, channel *xmlquery.Node) string {
+	// Namespace aware, RSS feeds use atom:link for paging
+	for _, expr := range c.PagingLinkWithNamespaceXPaths {
+		hrefs := collectLinkHrefsWithNamespace(r, expr, channel)
+		if len(hrefs) > 0 {
+			return hrefs[0]
+		}
+	}
+	return ""
+}
+
+// Follow paging links of newly followed feeds to collect their older posts
+func (c *Crawler) Backfill(r *colly.Request, channel *xmlquery.Node, isDirect bool) {
+	if c.Config.BackfillPages < 1 {
+		return
+	}
+	feed_url := feedUrlOf(r)
+	page := backfillPageOf(r)
+	if page == 0 {
+		// Only backfill once, when we start following the feed
+		if !isDirect || !c.db.StartBackfill(feed_url) {
+			return
+		}
+	}
+	if page >= c.Config.BackfillPages {
+		log.Printf("Backfill page limit reached: %s", feed_url)
+		return
+	}
+
+	next := c.findNextPage(r, channel)
+	if next == "" || next == r.URL.String() {
+		return
+	}
+	parsed, err := r.URL.Parse(next)
+	if err != nil {
+		return
+	}
+
+	log.Printf("Backfill page %d of %s: %s", page+1, feed_url, next)
+	ctx := colly.NewContext()
+	putNodeType(ctx, CTX_TARGET_TYPE, NODE_TYPE_FEED)
+	ctx.Put(CTX_BACKFILL_OF, feed_url)
+	ctx.Put(CTX_BACKFILL_FROM, requestedFeedUrlOf(r))
+	putInt(ctx, CTX_BACKFILL_PAGE, page+1)
+	c.Queue.AddRequest(&colly.Request{
+		URL:    parsed,
+		Method: "GET",
+		Depth:  r.Depth,
+		Ctx:    ctx,
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/antchfx/xmlquery"
+	"github.com/gocolly/colly/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	DATE_SOURCE_POST          = "post"
+	DATE_SOURCE_FIRST_SEEN    = "first_seen"
+	DATE_SOURCE_LAST_MODIFIED = "last_modified"
+	DATE_SOURCE_FEED          = "feed"
+	DATE_SOURCE_NONE          = "none"
+)
+
+var DATE_FALLBACKS = []string{
+	DATE_SOURCE_FIRST_SEEN,
+	DATE_SOURCE_LAST_MODIFIED,
+	DATE_SOURCE_FEED,
+}
+
+func parseDateFallbacks(fallbacks []string) []string {
+	if fallbacks == nil {
+		return []string{DATE_SOURCE_FIRST_SEEN}
+	}
+	for _, fallback := range fallbacks {
+		if !slices.Contains(DATE_FALLBACKS, fallback) {
+			panicf("Unknown date fallback: %s", fallback)
+		}
+	}
+	return fallbacks
+}
+
+// Keep the Last-Modified header for posts without dates
+func trackLastModified(r *colly.Request, headers *http.Header) {
+	if headers != nil && headers.Get("Last-Modified") != "" {
+		r.Ctx.Put(CTX_LAST_MODIFIED, headers.Get("Last-Modified"))
+	}
+}
+
+// Date a post, falling back when the feed doesn't give a usable date
+// channelDates are the feed level dates, in order of preference
+func (c *Crawler) datePost(r *colly.Request, post *PostFrontmatter, dateStr string, channel *xmlquery.Node, channelDates ...string) {
+	// Remember every post, its date may go missing later
+	// Items without a GUID are known by their link
+	key := post.guid
+	if key == "" {
+		key = post.Params.Link
+	}
+	firstSeen := c.db.FirstSeen(key, fetchedAt(r))
+
+	if _, err := ParseDate(dateStr); err == nil {
+		post.WithDate(fmtDate(dateStr))
+		post.WithDateSource(DATE_SOURCE_POST)
+		return
+	}
+	for _, fallback := range c.Config.DateFallbacks {
+		found := ""
+		switch fallback {
+		case DATE_SOURCE_FIRST_SEEN:
+			found = firstSeen.Format(time.RFC3339)
+		case DATE_SOURCE_LAST_MODIFIED:
+			found = r.Ctx.Get(CTX_LAST_MODIFIED)
+		case DATE_SOURCE_FEED:
+			if channel == nil {
+				continue
+			}
+			for _, path := range channelDates {
+				if found = xmlText(channel, path); found != "" {
+					break
+				}
+			}
+		}
+		if _, err := ParseDate(found); err == nil {
+			post.WithDate(fmtDate(found))
+			post.WithDateSource(fallback)
+			return
+		}
+	}
+	post.WithDate(OLD_DATE_RFC3339)
+	post.WithDateSource(DATE_SOURCE_NONE)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"slices"
+)
+
+// The shortest path from a seed to a node, starting at the seed
+// Empty when the node isn't reachable from a seed
+func (r *CrawlResults) provenance(url string, parents map[string]*LinkFrontmatter) []ProvenanceStep {
+	path := []ProvenanceStep{}
+	node := url
+	for {
+		parent, ok := parents[node]
+		if !ok {
+			break
+		}
+		path = append(path, r.provenanceStep(node, parent.Params.DestinationType, parent.Params.LinkType))
+		node = parent.Params.SourceURL
+	}
+	if !r.Seeds[node] {
+		return []ProvenanceStep{}
+	}
+	path = append(path, r.provenanceStep(node, NODE_TYPE_SEED, ""))
+	slices.Reverse(path)
+	return path
+}
+
+func (r *CrawlResults) provenanceStep(url string, nodeType NodeType, linkType string) ProvenanceStep {
+	step := ProvenanceStep{
+		Link:     url,
+		Type:     nodeType.String(),
+		LinkType: linkType,
+	}
+	if found, ok := r.Feeds[url]; ok {
+		step.Title = found.Feed.Title
+	} else if blogroll, ok := r.Blogrolls[url]; ok {
+		step.Title = blogroll.Title
+	}
+	return step
+}
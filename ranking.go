@@ -0,0 +1,164 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+const PAGERANK_DAMPING = 0.85
+const PAGERANK_MAX_ITERATIONS = 100
+const PAGERANK_TOLERANCE = 1e-9
+
+// Links that recommend another page, rather than naming a page twice
+func isRecommendation(link *LinkFrontmatter) bool {
+	return !isCanonicalLinkType(link.Params.LinkType)
+}
+
+// See: https://en.wikipedia.org/wiki/PageRank
+func (r *CrawlResults) pageRank() map[string]float64 {
+	nodes := map[string]bool{}
+	outDegree := map[string]int{}
+	for _, link := range r.Links {
+		nodes[link.Params.SourceURL] = true
+		nodes[link.Params.DestinationURL] = true
+		if isRecommendation(link) {
+			outDegree[link.Params.SourceURL]++
+		}
+	}
+	for url := range r.Feeds {
+		nodes[url] = true
+	}
+	if len(nodes) == 0 {
+		return map[string]float64{}
+	}
+
+	// Sum in a fixed order so ties rank the same every run
+	order := sortedKeys(nodes)
+	links := []*LinkFrontmatter{}
+	for _, id := range sortedKeys(r.Links) {
+		if isRecommendation(r.Links[id]) {
+			links = append(links, r.Links[id])
+		}
+	}
+
+	n := float64(len(nodes))
+	ranks := map[string]float64{}
+	for _, node := range order {
+		ranks[node] = 1 / n
+	}
+	for i := 0; i < PAGERANK_MAX_ITERATIONS; i++ {
+		// Pages without links share their rank with everyone
+		dangling := 0.0
+		for _, node := range order {
+			if outDegree[node] == 0 {
+				dangling += ranks[node]
+			}
+		}
+		next := map[string]float64{}
+		for _, node := range order {
+			next[node] = (1-PAGERANK_DAMPING)/n + PAGERANK_DAMPING*dangling/n
+		}
+		for _, link := range links {
+			source := link.Params.SourceURL
+			next[link.Params.DestinationURL] += PAGERANK_DAMPING * ranks[source] / float64(outDegree[source])
+		}
+		delta := 0.0
+		for _, node := range order {
+			delta += math.Abs(next[node] - ranks[node])
+		}
+		ranks = next
+		if delta < PAGERANK_TOLERANCE {
+			break
+		}
+	}
+	return ranks
+}
+
+// Feeds recommended by a feed, through its blogrolls, website and citations
+func (r *CrawlResults) recommendedFrom(feed string) []string {
+	found := []string{}
+	seen := map[string]bool{feed: true}
+	frontier := []string{feed}
+	foundFeed := func(url string) {
+		if !seen[url] {
+			seen[url] = true
+			found = append(found, url)
+		}
+	}
+	for len(frontier) > 0 {
+		node := frontier[0]
+		frontier = frontier[1:]
+		for _, link := range r.LinksFrom[node] {
+			next := link.Params.DestinationURL
+			if seen[next] || !isRecommendation(link) {
+				continue
+			}
+			if _, isFeed := r.Feeds[next]; isFeed {
+				// Don't follow the recommendations of other feeds
+				foundFeed(next)
+				continue
+			}
+			if link.Params.LinkType == LINK_TYPE_POST_CITES {
+				// A cited site recommends its own feeds, not the sites it cites
+				for _, alt := range r.LinksFrom[next] {
+					if _, isFeed := r.Feeds[alt.Params.DestinationURL]; isFeed && alt.Params.LinkType == LINK_TYPE_LINK_REL_ALT {
+						foundFeed(alt.Params.DestinationURL)
+					}
+				}
+				continue
+			}
+			seen[next] = true
+			frontier = append(frontier, next)
+		}
+	}
+	return found
+}
+
+// Score feeds by how central they are to the network
+func (c *Crawler) RankFeeds() {
+	results := c.Results
+	pageRanks := results.pageRank()
+
+	recommendedBy := map[string]int{}
+	for url, found := range results.Feeds {
+		if !found.IsDirect {
+			continue
+		}
+		for _, recommended := range results.recommendedFrom(url) {
+			recommendedBy[recommended]++
+		}
+	}
+
+	ranked := []*FeedResult{}
+	for url, found := range results.Feeds {
+		inDegree := map[string]bool{}
+		for _, link := range results.LinksTo[url] {
+			if isRecommendation(link) {
+				inDegree[link.Params.SourceURL] = true
+			}
+		}
+		found.Feed.WithRanking(len(inDegree), pageRanks[url], recommendedBy[url])
+		ranked = append(ranked, found)
+	}
+
+	slices.SortFunc(ranked, cmpFeedRank)
+	for i, found := range ranked {
+		found.Feed.WithRank(i + 1)
+	}
+}
+
+// Most recommended by feeds we follow first, then the most central
+func cmpFeedRank(a, b *FeedResult) int {
+	aParams, bParams := a.Feed.Params, b.Feed.Params
+	if byRecommended := cmp.Compare(bParams.RecommendedBy, aParams.RecommendedBy); byRecommended != 0 {
+		return byRecommended
+	}
+	if byPageRank := cmp.Compare(bParams.PageRank, aParams.PageRank); byPageRank != 0 {
+		return byPageRank
+	}
+	if byInDegree := cmp.Compare(bParams.InDegree, aParams.InDegree); byInDegree != 0 {
+		return byInDegree
+	}
+	return cmp.Compare(a.Order, b.Order)
+}
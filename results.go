@@ -0,0 +1,256 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+type FeedResult struct {
+	Feed     *FeedFrontmatter
+	IsDirect bool
+	// The order the feed was found in
+	Order int
+}
+
+// Everything found during the crawl
+//
+// Results are held until the crawl completes so that post-crawl passes
+// can see the whole network before anything is written.
+type CrawlResults struct {
+	lock      sync.Mutex
+	Feeds     map[string]*FeedResult          // By feed link
+	Posts     map[string]*PostFrontmatter     // By post ID
+	Links     map[string]*LinkFrontmatter     // By link ID
+	Blogrolls map[string]*BlogrollFrontmatter // By blogroll link
+
+	// URLs we started the crawl from
+	Seeds map[string]bool
+
+	// Links indexed by destination and source URL
+	LinksTo   map[string][]*LinkFrontmatter
+	LinksFrom map[string][]*LinkFrontmatter
+
+	// URL -> canonical URL, for merged duplicates
+	Canonicals map[string]string
+
+	// Everything block rules removed
+	Moderation []ModerationEntry
+
+	// Cited site -> sites with posts linking to it
+	Citations map[string]map[string]bool
+
+	// Sites joined by verified rel=me links, by identity ID
+	Identities map[string]*IdentityFrontmatter
+
+	// Clusters of tightly connected feeds, by community ID
+	Communities map[string]*Community
+
+	// Request URL -> when it was last fetched
+	NodeFetches map[string]NodeFetch
+}
+
+// A fetched URL, and what the request expected to find there
+type NodeFetch struct {
+	NodeType  NodeType
+	FetchedAt time.Time
+}
+
+func NewCrawlResults() *CrawlResults {
+	return &CrawlResults{
+		Feeds:       map[string]*FeedResult{},
+		Posts:       map[string]*PostFrontmatter{},
+		Links:       map[string]*LinkFrontmatter{},
+		Blogrolls:   map[string]*BlogrollFrontmatter{},
+		Seeds:       map[string]bool{},
+		LinksTo:     map[string][]*LinkFrontmatter{},
+		LinksFrom:   map[string][]*LinkFrontmatter{},
+		Canonicals:  map[string]string{},
+		Citations:   map[string]map[string]bool{},
+		Identities:  map[string]*IdentityFrontmatter{},
+		Communities: map[string]*Community{},
+		NodeFetches: map[string]NodeFetch{},
+	}
+}
+
+func (c *Crawler) SaveLink(f *LinkFrontmatter) {
+	c.Results.lock.Lock()
+	defer c.Results.lock.Unlock()
+	id := buildLinkId(f.Params.SourceURL, f.Params.DestinationURL)
+	if _, ok := c.Results.Links[id]; ok {
+		return
+	}
+	c.Results.Links[id] = f
+	c.Results.LinksTo[f.Params.DestinationURL] = append(c.Results.LinksTo[f.Params.DestinationURL], f)
+	c.Results.LinksFrom[f.Params.SourceURL] = append(c.Results.LinksFrom[f.Params.SourceURL], f)
+}
+
+func (c *Crawler) SaveFeed(f *FeedFrontmatter, isDirect bool) {
+	c.Results.lock.Lock()
+	defer c.Results.lock.Unlock()
+	order := len(c.Results.Feeds)
+	if found, ok := c.Results.Feeds[f.Params.FeedLink]; ok {
+		// Once followed, always followed
+		isDirect = isDirect || found.IsDirect
+		order = found.Order
+	}
+	c.Results.Feeds[f.Params.FeedLink] = &FeedResult{
+		Feed:     f,
+		IsDirect: isDirect,
+		Order:    order,
+	}
+}
+
+func (c *Crawler) SavePost(f *PostFrontmatter) {
+	c.Results.lock.Lock()
+	defer c.Results.lock.Unlock()
+	c.Results.Posts[f.Params.Id] = f
+}
+
+func (c *Crawler) SaveBlogroll(f *BlogrollFrontmatter) {
+	c.Results.lock.Lock()
+	defer c.Results.lock.Unlock()
+	c.Results.Blogrolls[f.Params.Link] = f
+}
+
+// Run the post-crawl passes and write the results
+func (c *Crawler) Publish() {
+	c.MergeDuplicates()
+	c.ClassifyFeeds()
+	c.ApplyIncludeRules()
+	c.RankFeeds()
+	// Before deduping, so kept copies of posts are from feeds we publish
+	c.limitRecommendations()
+	c.DedupePosts()
+	c.limitPosts()
+	c.VerifyIdentities()
+	c.DetectCommunities()
+
+	log.Printf("Writing %d feeds, %d posts, %d links, %d blogrolls and %d identities",
+		len(c.Results.Feeds), len(c.Results.Posts), len(c.Results.Links), len(c.Results.Blogrolls), len(c.Results.Identities))
+	for _, f := range c.Results.Links {
+		c.publishLink(f)
+	}
+	for _, f := range c.Results.Feeds {
+		c.publishFeed(f.Feed, f.IsDirect)
+	}
+	for _, f := range c.Results.Posts {
+		c.publishPost(f)
+	}
+	for _, f := range c.Results.Blogrolls {
+		c.publishBlogroll(f)
+	}
+	for _, f := range c.Results.Identities {
+		c.publishIdentity(f)
+	}
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
+		for url, canonical := range c.Results.Canonicals {
+			c.db.TrackCanonical(url, canonical)
+		}
+		for _, entry := range c.Results.Moderation {
+			c.db.TrackModeration(entry)
+		}
+		c.db.ClearCommunities()
+		for _, community := range c.Results.Communities {
+			c.db.TrackCommunity(community)
+		}
+		c.publishNodeFetches()
+	}
+	c.ExportGraph()
+	c.WriteSuggestions()
+	c.ReportChanges()
+}
+
+func (c *Crawler) TrackNodeFetch(url string, nodeType NodeType, fetchedAt time.Time) {
+	c.Results.lock.Lock()
+	defer c.Results.lock.Unlock()
+	c.Results.NodeFetches[url] = NodeFetch{nodeType, fetchedAt}
+}
+
+// Record fetches against the URLs and node types the published rows use
+// Feeds may be known by their self link or a merged duplicate's URL, and
+// seeds are recorded as the feed, blogroll or website they turned out to be
+func (c *Crawler) publishNodeFetches() {
+	results := c.Results
+	fetches := map[string]NodeFetch{}
+	for _, url := range sortedKeys(results.NodeFetches) {
+		fetch := results.NodeFetches[url]
+		if _, ok := results.Blogrolls[url]; ok {
+			fetch.NodeType = NODE_TYPE_BLOGROLL
+		} else {
+			if canonical, ok := results.Canonicals[url]; ok {
+				url = canonical
+			}
+			if _, ok := results.Feeds[url]; ok {
+				fetch.NodeType = NODE_TYPE_FEED
+			} else if _, ok := results.Blogrolls[url]; ok {
+				fetch.NodeType = NODE_TYPE_BLOGROLL
+			} else if fetch.NodeType == NODE_TYPE_SEED {
+				fetch.NodeType = NODE_TYPE_WEBSITE
+			}
+		}
+		if previous, ok := fetches[url]; !ok || fetch.FetchedAt.After(previous.FetchedAt) {
+			fetches[url] = fetch
+		}
+	}
+	for url, fetch := range fetches {
+		c.db.TrackNodeFetch(url, fetch.NodeType, fetch.FetchedAt)
+	}
+}
+
+func (c *Crawler) publishLink(f *LinkFrontmatter) {
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_HUGO_CONTENT) {
+		id := buildLinkId(f.Params.SourceURL, f.Params.DestinationURL)
+		path := generatedFilePath(c.Config.NetworkFolderName, LINK_PREFIX, id)
+		writeYaml(f, path)
+	}
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
+		c.db.TrackLink(f)
+	}
+}
+
+func (c *Crawler) publishFeed(f *FeedFrontmatter, isDirect bool) {
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_HUGO_CONTENT) {
+		var path string
+		if isDirect {
+			path = generatedFilePath(c.Config.FollowingFolderName, FEED_PREFIX, f.Params.Id)
+		} else {
+			path = generatedFilePath(c.Config.DiscoverFolderName, FEED_PREFIX, f.Params.Id)
+		}
+		writeYaml(f, path)
+	}
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
+		c.db.TrackFeed(f)
+	}
+}
+
+func (c *Crawler) publishPost(f *PostFrontmatter) {
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_HUGO_CONTENT) {
+		path := generatedFilePath(c.Config.ReadingFolderName, POST_PREFIX, f.Params.Id)
+		writeYaml(f, path)
+	}
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
+		c.db.TrackPost(f)
+	}
+}
+
+func (c *Crawler) publishBlogroll(f *BlogrollFrontmatter) {
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_HUGO_CONTENT) {
+		path := generatedFilePath(c.Config.BlogrollFolderName, BLOGROLL_PREFIX, f.Params.Id)
+		writeYaml(f, path)
+	}
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
+		c.db.TrackBlogroll(f)
+	}
+}
+
+func (c *Crawler) publishIdentity(f *IdentityFrontmatter) {
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_HUGO_CONTENT) {
+		path := generatedFilePath(c.Config.IdentityFolderName, IDENTITY_PREFIX, f.Params.Id)
+		writeYaml(f, path)
+	}
+	if slices.Contains(c.Config.OutputModes, OUTPUT_MODE_SQL) {
+		c.db.TrackIdentity(f)
+	}
+}
@@ -11,7 +11,8 @@ import (
 
 func (c *Crawler) OnXML_RssChannel(headers *http.Header, r *colly.Request, channel *xmlquery.Node) {
 	isPodcast := false
-	feed_url := r.URL.String()
+	hub, self := c.findHubAndSelf(r, channel)
+	feed_url := c.canonicalFeedUrl(r, self)
 
 	link := xmlText(channel, "link[not(@rel=\"next\")]")
 	title := xmlText(channel, "title")
@@ -19,6 +20,18 @@ func (c *Crawler) OnXML_RssChannel(headers *http.Header, r *colly.Request, chann
 	date := fmtDate(xmlText(channel, "pubDate"))
 	language := xmlText(channel, "language")
 
+	override := c.Config.OverrideFor(feed_url, r.URL.String(), link)
+	if override.Language != nil {
+		language = *override.Language
+	}
+
+	if isBackfillPage(r) {
+		// An older page of a feed we've already processed
+		c.CollectRssItems(r, channel, link, language, override)
+		c.Backfill(r, channel, true)
+		return
+	}
+
 	// Podcasts may use iTunes categories
 	categories := xmlPathAttrMultipleWithNamespace(channel, c.ITunesCategoryWithNamespaceXPath, "text")
 	if len(categories) > 0 {
@@ -27,6 +40,7 @@ func (c *Crawler) OnXML_RssChannel(headers *http.Header, r *colly.Request, chann
 	} else {
 		categories = xmlTextMultiple(channel, "category")
 	}
+	categories = c.Config.Categories.Normalize(categories)
 
 	// First try a namespace aware query for blogroll
 	blogrolls := xmlquery.QuerySelectorAll(channel, c.BlogrollWithNamespaceXPath)
@@ -48,29 +62,38 @@ func (c *Crawler) OnXML_RssChannel(headers *http.Header, r *colly.Request, chann
 	feed.WithLink(link)
 	feed.WithFeedType("rss")
 	feed.WithBlogRolls(blogrollUrls)
+	feed.WithItemLinks(xmlTextMultiple(channel, "item/link"))
 	feed.WithCategories(categories)
 	feed.WithLanguage(language)
+	feed.WithHub(hub)
+	feed.WithSelfLink(self)
 	feed.IsPodcast(isPodcast)
 	setNoArchive(feed, headers)
+	feed.WithOverride(override)
 
 	if blocked, domain := isBlockedDomain(link, c.Config); blocked {
 		log.Printf("Domain is blocked: %s", domain)
 		return
 	}
-	if blocked, blockWord := hasBlockWords(title, c.Config); blocked {
-		log.Printf("Word in title is blocked: %s", blockWord)
+	blockable := &Blockable{
+		Title:       title,
+		Description: description,
+		Categories:  categories,
+	}
+	if c.isBlocked("feed", feed_url, blockable, override) {
 		return
 	}
-	if blocked, blockWord := hasBlockWords(description, c.Config); blocked {
-		log.Printf("Word in description is blocked: %s", blockWord)
+	if c.isBlockedCategory("feed", feed_url, title, feed.Params.Categories) {
 		return
 	}
 	if isBlockedPost(link, title, feed.Params.Id, c.Config) {
 		return
 	}
 
-	log.Println("DEPTH:", r.Depth)
-	isDirect := r.Depth < 4
+	c.TrackSelfLink(r, feed_url)
+
+	// Refined using the whole link graph after the crawl
+	isDirect := c.IsFollowed(requestedFeedUrlOf(r))
 
 	// Check for blogrolls
 	for _, blogroll := range blogrollUrls {
@@ -83,42 +106,56 @@ func (c *Crawler) OnXML_RssChannel(headers *http.Header, r *colly.Request, chann
 		c.Request(NODE_TYPE_FEED, feed_url, NODE_TYPE_WEBSITE, link, LINK_TYPE_FROM_FEED, r.Depth+1)
 	}
 
-	postCount, avgPostLen, avgPostPerDay := c.CollectRssItems(r, channel, language)
+	postCount, avgPostLen, avgPostPerDay, futurePosts := c.CollectRssItems(r, channel, link, language, override)
 	feed.WithPostCount(postCount)
 	feed.WithAvgPostLen(avgPostLen)
 	feed.WithAvgPostPerDay(avgPostPerDay)
+	feed.WithFuturePosts(futurePosts)
 	c.SaveFeed(feed, isDirect)
+	c.Backfill(r, channel, isDirect)
+	if isDirect {
+		c.TrackWebSub(feed, r.Depth)
+	}
 }
 
-func (c *Crawler) CollectRssItems(r *colly.Request, channel *xmlquery.Node, feed_language string) (int, int, float32) {
+func (c *Crawler) CollectRssItems(r *colly.Request, channel *xmlquery.Node, website, feed_language string, override FeedOverride) (int, int, float32, int) {
 	if r.Depth > c.Config.PostCollectionDepth {
-		return 0, 0, 0.0
+		return 0, 0, 0.0, 0
 	}
-	if c.Config.MaxPostsPerFeed < 1 {
-		return 0, 0, 0.0
+	maxPostsPerFeed := intDefault(override.MaxPostsPerFeed, c.Config.MaxPostsPerFeed)
+	if maxPostsPerFeed < 1 {
+		return 0, 0, 0.0, 0
 	}
 
 	posts := []*PostFrontmatter{}
+	futurePosts := 0
 	xmlItems := xmlquery.Find(channel, "//item")
 
 	for _, item := range xmlItems {
-		post, ok := c.OnXML_RssItem(r, item, feed_language)
+		post, ok := c.OnXML_RssItem(r, item, website, feed_language, override)
 		if ok {
 			posts = append(posts, post)
 		}
 	}
 
+	// Misconfigured feeds may have posts dated years ahead
+	posts = slices.DeleteFunc(posts, func(post *PostFrontmatter) bool {
+		keep, isFuture := c.checkFutureDate(r, post)
+		if isFuture {
+			futurePosts++
+		}
+		return !keep
+	})
+
 	slices.SortFunc(posts, func(a, b *PostFrontmatter) int {
 		// Reverse chronological
 		return cmpDateStr(b.Date, a.Date)
 	})
 
-	// TODO remove posts from the future
-
 	postLenSum := 0
 	for i, post := range posts {
 		postLenSum += len(post.Params.Content)
-		if i < c.Config.MaxPostsPerFeed {
+		if i < maxPostsPerFeed && !boolDefault(override.HidePosts, false) {
 			c.SavePost(post)
 		}
 	}
@@ -145,51 +182,61 @@ func (c *Crawler) CollectRssItems(r *colly.Request, channel *xmlquery.Node, feed
 
 	// TODO: Additional stats: oldest post, newest pos
 	// TODO: lastActive
-	return numPosts, avgPostLen, avgPostPerDay
+	return numPosts, avgPostLen, avgPostPerDay, futurePosts
 }
 
-func (c *Crawler) OnXML_RssItem(r *colly.Request, item *xmlquery.Node, feed_language string) (*PostFrontmatter, bool) {
-	feed_url := r.URL.String()
+func (c *Crawler) OnXML_RssItem(r *colly.Request, item *xmlquery.Node, website, feed_language string, override FeedOverride) (*PostFrontmatter, bool) {
+	feed_url := feedUrlOf(r)
 
 	post_id := xmlText(item, "guid")
 	link := xmlText(item, "link")
 	title := xmlText(item, "title")
 	description := xmlText(item, "description")
-	date := fmtDate(xmlText(item, "pubDate"))
+	dateStr := xmlText(item, "pubDate")
 	content := xmlText(item, "content")
-	categories := xmlTextMultiple(item, "category")
+	categories := c.Config.Categories.Normalize(xmlTextMultiple(item, "category"))
+	author := xmlText(item, "author")
+	if author == "" {
+		author = xmlTextWithNamespace(item, c.DcCreatorWithNamespaceXPath)
+	}
 
 	post := NewPostFrontmatter(feed_url, post_id, link)
 	post.WithTitle(title)
 	post.WithDescription(description)
-	post.WithDate(date)
 	post.WithContent(content)
 	post.WithFeedLink(feed_url)
 	post.WithCategories(categories)
+	post.WithCategories(override.Categories)
+	post.WithAuthor(author)
 
 	// TODO: Should we try xml:lang too?
 	post.WithLanguage(feed_language)
+	if post.Params.Language == "" {
+		post.WithLanguage(detectPostLanguage(post))
+	}
 
 	if title == "" {
 		return nil, false
 	}
-	if blocked, domain := isBlockedDomain(link, c.Config); blocked {
-		log.Printf("Domain is blocked: %s", domain)
-		return nil, false
+	blockable := &Blockable{
+		Title:       title,
+		Description: description,
+		Content:     content,
+		Author:      author,
+		Categories:  post.Params.Categories,
 	}
-	if blocked, blockWord := hasBlockWords(title, c.Config); blocked {
-		log.Printf("Word in title is blocked: %s", blockWord)
+	if c.isBlocked("post", link, blockable, override) {
 		return nil, false
 	}
-	if blocked, blockWord := hasBlockWords(description, c.Config); blocked {
-		log.Printf("Word in description is blocked: %s", blockWord)
+	if c.isBlockedCategory("post", link, title, post.Params.Categories) {
 		return nil, false
 	}
-	if blocked, blockWord := hasBlockWords(content, c.Config); blocked {
-		log.Printf("Word in content is blocked: %s", blockWord)
+	inclusion := c.inclusionOf(link, blockable, post.Params.Language)
+	if inclusion == INCLUDE_NEVER {
 		return nil, false
 	}
-	if isBlockedPost(link, title, post.Params.Id, c.Config) {
+	post.WithInclusion(inclusion)
+	if !c.isAllowedLanguage(link, post.Params.LanguageTag) {
 		return nil, false
 	}
 	if strings.HasPrefix(link, "/") {
@@ -201,5 +248,18 @@ func (c *Crawler) OnXML_RssItem(r *colly.Request, item *xmlquery.Node, feed_lang
 		return nil, false
 	}
 
+	// Only clean up links of posts we keep, resolving redirectors is a request
+	link = c.CleanPostLink(link)
+	post.WithLink(link)
+	if isBlockedPost(link, title, post.Params.Id, c.Config) {
+		return nil, false
+	}
+	if blocked, domain := isBlockedDomain(link, c.Config); blocked {
+		log.Printf("Domain is blocked: %s", domain)
+		return nil, false
+	}
+
+	c.TrackCitations(r, website, link, description, content)
+	c.datePost(r, post, dateStr, item.Parent, "pubDate", "lastBuildDate")
 	return post, true
 }
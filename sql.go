@@ -1,6 +1,11 @@
 package main
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -36,6 +41,7 @@ func (db *DB) TrackBlogroll(fm *BlogrollFrontmatter) {
 		Title:       fm.Title,
 		BlogrollId:  fm.Params.Id,
 		Link:        fm.Params.Link,
+		NodeId:      db.TrackNode(fm.Params.Link, NODE_TYPE_BLOGROLL),
 	}
 
 	result := db.db.
@@ -43,7 +49,7 @@ func (db *DB) TrackBlogroll(fm *BlogrollFrontmatter) {
 			clause.OnConflict{
 				Columns: []clause.Column{{Name: "link"}},
 				DoUpdates: clause.AssignmentColumns([]string{
-					"date", "description", "title",
+					"date", "description", "title", "node_id",
 				}),
 			}).
 		Create(&blogroll)
@@ -57,12 +63,25 @@ func (db *DB) TrackFeed(fm *FeedFrontmatter) {
 		Title:         fm.Title,
 		FeedLink:      fm.Params.FeedLink,
 		FeedId:        fm.Params.Id,
+		NodeId:        db.TrackNode(fm.Params.FeedLink, NODE_TYPE_FEED),
 		FeedType:      fm.Params.FeedType,
 		IsPodcast:     fm.Params.IsPodcast,
 		IsNoarchive:   fm.Params.IsNoarchive,
 		PostCount:     fm.Params.PostCount,
 		AvgPostLen:    fm.Params.AvgPostLen,
 		AvgPostPerDay: fm.Params.AvgPostPerDay,
+		FuturePosts:   fm.Params.FuturePosts,
+		Hub:           fm.Params.Hub,
+		SelfLink:      fm.Params.SelfLink,
+		Distance:      fm.Params.Distance,
+		InDegree:      fm.Params.InDegree,
+		PageRank:      fm.Params.PageRank,
+		RecommendedBy: fm.Params.RecommendedBy,
+		Rank:          fm.Params.Rank,
+		Identity:      fm.Params.Identity,
+		Community:     fm.Params.Community,
+		Priority:      fm.Params.Priority,
+		HidePosts:     fm.Params.HidePosts,
 	}
 
 	result := db.db.
@@ -71,6 +90,9 @@ func (db *DB) TrackFeed(fm *FeedFrontmatter) {
 				Columns: []clause.Column{{Name: "feed_link"}},
 				DoUpdates: clause.AssignmentColumns([]string{
 					"date", "description", "title", "is_podcast", "is_noarchive",
+					"hub", "self_link", "distance", "priority", "hide_posts",
+					"future_posts", "in_degree", "page_rank", "recommended_by", "rank",
+					"identity", "community", "node_id",
 				}),
 			}).
 		Create(&feed)
@@ -90,6 +112,44 @@ func (db *DB) TrackFeed(fm *FeedFrontmatter) {
 		ohno(result.Error)
 	}
 
+	// Replace the path, it may be shorter this time
+	result = db.db.
+		Where("feed_link = ?", fm.Params.FeedLink).
+		Delete(&FeedProvenance{})
+	ohno(result.Error)
+	if len(fm.Params.FoundVia) > 0 {
+		steps := []FeedProvenance{}
+		for i, step := range fm.Params.FoundVia {
+			steps = append(steps, FeedProvenance{
+				FeedLink: fm.Params.FeedLink,
+				Step:     i,
+				Link:     step.Link,
+				NodeType: step.Type,
+				Title:    step.Title,
+				LinkType: step.LinkType,
+			})
+		}
+		result = db.db.Create(&steps)
+		ohno(result.Error)
+	}
+
+	// Replace the mutual recommendations, feeds may stop recommending each other
+	result = db.db.
+		Where("feed_link = ?", fm.Params.FeedLink).
+		Delete(&MutualRecommendation{})
+	ohno(result.Error)
+	if len(fm.Params.MutualRecommendations) > 0 {
+		mutual := []MutualRecommendation{}
+		for _, other := range fm.Params.MutualRecommendations {
+			mutual = append(mutual, MutualRecommendation{
+				FeedLink:  fm.Params.FeedLink,
+				OtherLink: other,
+			})
+		}
+		result = db.db.Create(&mutual)
+		ohno(result.Error)
+	}
+
 	if len(fm.Params.Language) > 0 {
 		result = db.db.
 			Clauses(clause.OnConflict{DoNothing: true}).
@@ -151,18 +211,65 @@ func (db *DB) TrackPost(fm *PostFrontmatter) {
 
 func (db *DB) TrackLink(fm *LinkFrontmatter) {
 	link := Link{
-		SourceType:      int(fm.Params.SourceType),
-		SourceUrl:       fm.Params.SourceURL,
-		DestinationType: int(fm.Params.DestinationType),
-		DestinationUrl:  fm.Params.DestinationURL,
-		LinkType:        fm.Params.LinkType,
+		SourceType:        int(fm.Params.SourceType),
+		SourceUrl:         fm.Params.SourceURL,
+		SourceNodeId:      db.TrackNode(fm.Params.SourceURL, fm.Params.SourceType),
+		DestinationType:   int(fm.Params.DestinationType),
+		DestinationUrl:    fm.Params.DestinationURL,
+		DestinationNodeId: db.TrackNode(fm.Params.DestinationURL, fm.Params.DestinationType),
+		LinkType:          fm.Params.LinkType,
+		Verified:          fm.Params.Verified,
 	}
 	result := db.db.
-		Clauses(clause.OnConflict{DoNothing: true}).
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{
+					{Name: "source_type"}, {Name: "source_url"},
+					{Name: "destination_type"}, {Name: "destination_url"},
+				},
+				DoUpdates: clause.AssignmentColumns([]string{"verified", "source_node_id", "destination_node_id"}),
+			}).
 		Create(&link)
 	ohno(result.Error)
 }
 
+// Returns the ID of the node for a URL in a role, adding it when it's new
+func (db *DB) TrackNode(link string, nodeType NodeType) uint {
+	node := Node{
+		Url:       link,
+		NodeType:  int(nodeType),
+		FirstSeen: time.Now(),
+	}
+	result := db.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&node)
+	ohno(result.Error)
+	if result.RowsAffected == 0 {
+		result = db.db.
+			Where("url = ? AND node_type = ?", node.Url, node.NodeType).
+			Take(&node)
+		ohno(result.Error)
+	}
+	return node.ID
+}
+
+func (db *DB) TrackNodeFetch(link string, nodeType NodeType, fetchedAt time.Time) {
+	node := Node{
+		Url:         link,
+		NodeType:    int(nodeType),
+		FirstSeen:   fetchedAt,
+		LastFetched: &fetchedAt,
+	}
+	result := db.db.
+		Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "url"}, {Name: "node_type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"last_fetched"}),
+			}).
+		Create(&node)
+	ohno(result.Error)
+}
+
 func (db *DB) TrackNoIndex(link string) {
 	noindex := Noindex{
 		Link: link,
@@ -190,6 +297,162 @@ func (db *DB) DeleteNoIndexLinks() {
 	ohno(err)
 }
 
+func (db *DB) TrackSourceError(link, handler string, err error) {
+	sourceError := SourceError{
+		Link:    link,
+		Handler: handler,
+		Error:   err.Error(),
+	}
+	result := db.db.
+		Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "link"}},
+				DoUpdates: clause.AssignmentColumns([]string{"handler", "error"}),
+			}).
+		Create(&sourceError)
+	ohno(result.Error)
+}
+
+func (db *DB) TrackFetch(link string, fetchedAt time.Time, hints RefreshHints, robotsTag, lastModified string, body []byte) {
+	skipHours := []string{}
+	for _, hour := range hints.SkipHours {
+		skipHours = append(skipHours, strconv.Itoa(hour))
+	}
+	fetch := FeedFetch{
+		FeedLink:       link,
+		FetchedAt:      fetchedAt,
+		RefreshMinutes: int(hints.Refresh / time.Minute),
+		SkipHours:      strings.Join(skipHours, ","),
+		SkipDays:       strings.Join(hints.SkipDays, ","),
+		RobotsTag:      robotsTag,
+		LastModified:   lastModified,
+		Body:           body,
+	}
+	result := db.db.
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "feed_link"}},
+				DoUpdates: clause.AssignmentColumns([]string{
+					"fetched_at", "refresh_minutes", "skip_hours", "skip_days", "robots_tag", "last_modified", "body",
+				}),
+			}).
+		Create(&fetch)
+	ohno(result.Error)
+}
+
+func (db *DB) GetFetch(link string) (*FeedFetch, bool) {
+	fetch := FeedFetch{}
+	result := db.db.Where("feed_link = ?", link).First(&fetch)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, false
+	}
+	ohno(result.Error)
+	return &fetch, true
+}
+
+// When a post was first seen, recording it as seen now if it's new
+func (db *DB) FirstSeen(guid string, now time.Time) time.Time {
+	seen := PostSeen{
+		Guid:      guid,
+		FirstSeen: now,
+	}
+	result := db.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&seen)
+	ohno(result.Error)
+	if result.RowsAffected > 0 {
+		return now
+	}
+	result = db.db.Where("guid = ?", guid).First(&seen)
+	ohno(result.Error)
+	return seen.FirstSeen
+}
+
+// Record that we've started backfilling a feed
+// Returns false if the feed was backfilled in an earlier crawl
+func (db *DB) StartBackfill(link string) bool {
+	backfill := Backfill{
+		FeedLink:  link,
+		StartedAt: time.Now(),
+	}
+	result := db.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&backfill)
+	ohno(result.Error)
+	return result.RowsAffected > 0
+}
+
+func (db *DB) TrackCanonical(link, canonical string) {
+	c := Canonical{
+		Link:         link,
+		CanonicalUrl: canonical,
+	}
+	result := db.db.
+		Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "link"}},
+				DoUpdates: clause.AssignmentColumns([]string{"canonical_url"}),
+			}).
+		Create(&c)
+	ohno(result.Error)
+}
+
+func (db *DB) TrackModeration(entry ModerationEntry) {
+	m := ModerationLog{
+		Kind:      entry.Kind,
+		Link:      entry.Link,
+		Title:     entry.Title,
+		Rule:      entry.Rule,
+		Field:     entry.Field,
+		BlockedAt: time.Now(),
+	}
+	result := db.db.Create(&m)
+	ohno(result.Error)
+}
+
+func (db *DB) TrackIdentity(fm *IdentityFrontmatter) {
+	sites := []IdentitySite{}
+	for _, site := range fm.Params.Sites {
+		sites = append(sites, IdentitySite{
+			IdentityId: fm.Params.Id,
+			Title:      fm.Title,
+			Link:       site,
+		})
+	}
+	// Replace the sites, they may have left this identity or joined it from another
+	result := db.db.
+		Where("identity_id = ? OR link IN ?", fm.Params.Id, fm.Params.Sites).
+		Delete(&IdentitySite{})
+	ohno(result.Error)
+	result = db.db.Create(&sites)
+	ohno(result.Error)
+}
+
+// Community IDs change with their members, so communities are rebuilt every run
+func (db *DB) ClearCommunities() {
+	result := db.db.
+		Where("1 = 1").
+		Delete(&CommunityCategory{})
+	ohno(result.Error)
+}
+
+func (db *DB) TrackCommunity(community *Community) {
+	if len(community.Categories) == 0 {
+		return
+	}
+	cats := []CommunityCategory{}
+	for i, category := range community.Categories {
+		cats = append(cats, CommunityCategory{
+			Community: community.Id,
+			Category:  category.Category,
+			Feeds:     category.Feeds,
+			Rank:      i + 1,
+		})
+	}
+	result := db.db.Create(&cats)
+	ohno(result.Error)
+}
+
 type Blogroll struct {
 	ID          uint   `gorm:"primaryKey"`
 	Date        string // TODO: use time.Time
@@ -197,6 +460,7 @@ type Blogroll struct {
 	Title       string
 	Link        string `gorm:"unique"`
 	BlogrollId  string
+	NodeId      uint `gorm:"index"`
 }
 
 type Feed struct {
@@ -206,12 +470,25 @@ type Feed struct {
 	Title         string
 	FeedLink      string `gorm:"unique"`
 	FeedId        string
+	NodeId        uint `gorm:"index"`
 	FeedType      string
 	IsPodcast     bool
 	IsNoarchive   bool
 	PostCount     int
 	AvgPostLen    int
 	AvgPostPerDay float32
+	FuturePosts   int
+	Hub           string
+	SelfLink      string
+	Distance      int
+	InDegree      int
+	PageRank      float64
+	RecommendedBy int
+	Rank          int
+	Identity      string
+	Community     string
+	Priority      int
+	HidePosts     bool
 }
 
 type Post struct {
@@ -248,13 +525,126 @@ type PostsByLanguage struct {
 	Link     string `gorm:"uniqueIndex:uniquePostsByLang"`
 }
 
+// Each URL in the network, once for each role it plays
+// A URL can be a feed, a website and a canonical URL at the same time
+type Node struct {
+	ID          uint   `gorm:"primaryKey"`
+	Url         string `gorm:"uniqueIndex:uniqueNode"`
+	NodeType    int    `gorm:"uniqueIndex:uniqueNode"`
+	FirstSeen   time.Time
+	LastFetched *time.Time
+}
+
 type Link struct {
-	ID              uint   `gorm:"primaryKey"`
-	SourceType      int    `gorm:"uniqueIndex:uniqueLink"`
-	SourceUrl       string `gorm:"uniqueIndex:uniqueLink"`
-	DestinationType int    `gorm:"uniqueIndex:uniqueLink"`
-	DestinationUrl  string `gorm:"uniqueIndex:uniqueLink"`
-	LinkType        string
+	ID                uint   `gorm:"primaryKey"`
+	SourceType        int    `gorm:"uniqueIndex:uniqueLink"`
+	SourceUrl         string `gorm:"uniqueIndex:uniqueLink"`
+	SourceNodeId      uint   `gorm:"index"`
+	DestinationType   int    `gorm:"uniqueIndex:uniqueLink"`
+	DestinationUrl    string `gorm:"uniqueIndex:uniqueLink"`
+	DestinationNodeId uint   `gorm:"index"`
+	LinkType          string
+	Verified          bool
+}
+
+type SourceError struct {
+	ID      uint   `gorm:"primaryKey"`
+	Link    string `gorm:"unique"`
+	Handler string
+	Error   string
+}
+
+// Each item removed by a block rule
+type ModerationLog struct {
+	ID        uint `gorm:"primaryKey"`
+	Kind      string
+	Link      string
+	Title     string
+	Rule      string
+	Field     string
+	BlockedAt time.Time
+}
+
+type PostSeen struct {
+	ID        uint   `gorm:"primaryKey"`
+	Guid      string `gorm:"unique"`
+	FirstSeen time.Time
+}
+
+type FeedFetch struct {
+	ID             uint   `gorm:"primaryKey"`
+	FeedLink       string `gorm:"unique"`
+	FetchedAt      time.Time
+	RefreshMinutes int
+	SkipHours      string // Comma separated
+	SkipDays       string // Comma separated
+	RobotsTag      string
+	LastModified   string
+	Body           []byte
+}
+
+func (f *FeedFetch) RefreshHints() RefreshHints {
+	hints := RefreshHints{
+		Refresh: time.Duration(f.RefreshMinutes) * time.Minute,
+	}
+	for _, hour := range strings.Split(f.SkipHours, ",") {
+		parsed, err := strconv.Atoi(hour)
+		if err == nil {
+			hints.SkipHours = append(hints.SkipHours, parsed)
+		}
+	}
+	for _, day := range strings.Split(f.SkipDays, ",") {
+		if len(day) > 0 {
+			hints.SkipDays = append(hints.SkipDays, day)
+		}
+	}
+	return hints
+}
+
+type Backfill struct {
+	ID        uint   `gorm:"primaryKey"`
+	FeedLink  string `gorm:"unique"`
+	StartedAt time.Time
+}
+
+type Canonical struct {
+	ID           uint   `gorm:"primaryKey"`
+	Link         string `gorm:"unique"`
+	CanonicalUrl string
+}
+
+// Sites joined by verified rel=me links
+type IdentitySite struct {
+	ID         uint `gorm:"primaryKey"`
+	IdentityId string
+	Title      string
+	Link       string `gorm:"unique"`
+}
+
+// Each page on the shortest path from a seed to a feed, in order
+type FeedProvenance struct {
+	ID       uint   `gorm:"primaryKey"`
+	FeedLink string `gorm:"uniqueIndex:uniqueProvenance"`
+	Step     int    `gorm:"uniqueIndex:uniqueProvenance"`
+	Link     string
+	NodeType string
+	Title    string
+	LinkType string
+}
+
+// The top shared categories of each community
+type CommunityCategory struct {
+	ID        uint   `gorm:"primaryKey"`
+	Community string `gorm:"uniqueIndex:uniqueCommunityCat"`
+	Category  string `gorm:"uniqueIndex:uniqueCommunityCat"`
+	Feeds     int
+	Rank      int
+}
+
+type MutualRecommendation struct {
+	ID        uint   `gorm:"primaryKey"`
+	FeedLink  string `gorm:"uniqueIndex:uniqueMutual"`
+	OtherLink string `gorm:"uniqueIndex:uniqueMutual"`
 }
 
 type Noindex struct {
@@ -263,6 +653,7 @@ type Noindex struct {
 }
 
 func (db *DB) Init() {
+	db.db.AutoMigrate(&Node{})
 	db.db.AutoMigrate(&Link{})
 	db.db.AutoMigrate(&Feed{})
 	db.db.AutoMigrate(&Post{})
@@ -272,4 +663,14 @@ func (db *DB) Init() {
 	db.db.AutoMigrate(&PostsByCategory{})
 	db.db.AutoMigrate(&PostsByLanguage{})
 	db.db.AutoMigrate(&Noindex{})
+	db.db.AutoMigrate(&SourceError{})
+	db.db.AutoMigrate(&FeedFetch{})
+	db.db.AutoMigrate(&Backfill{})
+	db.db.AutoMigrate(&Canonical{})
+	db.db.AutoMigrate(&ModerationLog{})
+	db.db.AutoMigrate(&PostSeen{})
+	db.db.AutoMigrate(&IdentitySite{})
+	db.db.AutoMigrate(&MutualRecommendation{})
+	db.db.AutoMigrate(&FeedProvenance{})
+	db.db.AutoMigrate(&CommunityCategory{})
 }
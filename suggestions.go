@@ -0,0 +1,215 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const DEFAULT_SUGGESTIONS_FOLDER = "static/suggestions"
+
+// How much sharing all categories with feeds we follow is worth,
+// compared to being listed next to one followed feed in a small blogroll
+const SUGGESTION_CATEGORY_WEIGHT = 0.5
+
+// A feed we don't follow that's often listed with feeds we do
+type Suggestion struct {
+	FeedLink string
+	Title    string
+	HtmlUrl  string
+	Score    float64
+	// Followed feeds listed in the same blogrolls
+	CoListedWith []string
+	Blogrolls    []string
+	// Categories also used by feeds we follow
+	SharedCategories []string
+}
+
+// Recommend feeds by how often blogrolls list them with the feeds we follow
+//
+// Each blogroll adds the number of followed feeds it lists, divided by
+// log2(1 + outlines) so a blogroll listing everything counts for less.
+// Feeds using the same categories as the feeds we follow score a bit more.
+func (c *Crawler) suggestFeeds() []*Suggestion {
+	r := c.Results
+	// Outlines aren't normalized, so match feeds by site key
+	feedsByKey := map[string]string{}
+	for feedLink, found := range r.Feeds {
+		feedsByKey[siteKey(feedLink)] = feedLink
+		for _, alias := range found.Feed.Params.Aliases {
+			feedsByKey[siteKey(alias)] = feedLink
+		}
+	}
+	for link, canonical := range r.Canonicals {
+		if _, ok := r.Feeds[canonical]; ok {
+			feedsByKey[siteKey(link)] = canonical
+		}
+	}
+	feedOf := func(xmlUrl string) string {
+		if feedLink, ok := feedsByKey[siteKey(xmlUrl)]; ok {
+			return feedLink
+		}
+		return xmlUrl
+	}
+
+	// Feeds removed by block rules
+	moderated := map[string]bool{}
+	for _, entry := range r.Moderation {
+		if entry.Kind == "feed" {
+			moderated[feedOf(entry.Link)] = true
+		}
+	}
+
+	followedCategories := map[string]bool{}
+	for _, found := range r.Feeds {
+		if found.IsDirect {
+			for _, category := range found.Feed.Params.Categories {
+				followedCategories[category] = true
+			}
+		}
+	}
+
+	suggestions := map[string]*Suggestion{}
+	for _, blogrollLink := range sortedKeys(r.Blogrolls) {
+		if r.Seeds[blogrollLink] {
+			// Our own blogroll only lists what we follow
+			continue
+		}
+		outlines := map[string]BlogrollOutline{}
+		for _, outline := range r.Blogrolls[blogrollLink].Params.Outlines {
+			if outline.XmlUrl != "" {
+				outlines[feedOf(outline.XmlUrl)] = outline
+			}
+		}
+		followed := []string{}
+		candidates := []string{}
+		for _, feedLink := range sortedKeys(outlines) {
+			if found, ok := r.Feeds[feedLink]; ok && found.IsDirect {
+				followed = append(followed, feedLink)
+			} else if blocked, _ := isBlockedDomain(feedLink, c.Config); !blocked && !moderated[feedLink] {
+				candidates = append(candidates, feedLink)
+			}
+		}
+		if len(followed) == 0 {
+			continue
+		}
+		weight := float64(len(followed)) / math.Log2(1+float64(len(outlines)))
+		for _, feedLink := range candidates {
+			suggestion, ok := suggestions[feedLink]
+			if !ok {
+				outline := outlines[feedLink]
+				suggestion = &Suggestion{
+					FeedLink: feedLink,
+					Title:    outline.Text,
+					HtmlUrl:  outline.HtmlUrl,
+				}
+				suggestions[feedLink] = suggestion
+			}
+			suggestion.Score += weight
+			suggestion.Blogrolls = append(suggestion.Blogrolls, blogrollLink)
+			for _, other := range followed {
+				if !slices.Contains(suggestion.CoListedWith, other) {
+					suggestion.CoListedWith = append(suggestion.CoListedWith, other)
+				}
+			}
+		}
+	}
+
+	ranked := []*Suggestion{}
+	for _, suggestion := range suggestions {
+		if found, ok := r.Feeds[suggestion.FeedLink]; ok {
+			// Prefer what the feed says about itself
+			if found.Feed.Title != "" {
+				suggestion.Title = found.Feed.Title
+			}
+			if found.Feed.Params.Link != "" {
+				suggestion.HtmlUrl = found.Feed.Params.Link
+			}
+			categories := found.Feed.Params.Categories
+			for _, category := range categories {
+				if followedCategories[category] {
+					suggestion.SharedCategories = append(suggestion.SharedCategories, category)
+				}
+			}
+			if len(categories) > 0 {
+				suggestion.Score += SUGGESTION_CATEGORY_WEIGHT * float64(len(suggestion.SharedCategories)) / float64(len(categories))
+			}
+		}
+		slices.Sort(suggestion.CoListedWith)
+		ranked = append(ranked, suggestion)
+	}
+	slices.SortFunc(ranked, func(a, b *Suggestion) int {
+		if byScore := cmp.Compare(b.Score, a.Score); byScore != 0 {
+			return byScore
+		}
+		return cmp.Compare(a.FeedLink, b.FeedLink)
+	})
+	if len(ranked) > c.Config.MaxSuggestions {
+		ranked = ranked[:c.Config.MaxSuggestions]
+	}
+	return ranked
+}
+
+// Write feeds you might like, to review and add to feed_urls
+func (c *Crawler) WriteSuggestions() {
+	if !c.Config.Suggestions {
+		return
+	}
+	suggestions := c.suggestFeeds()
+	folder := c.Config.SuggestionsFolder
+	err := os.MkdirAll(folder, os.FileMode(0755))
+	ohno(err)
+	log.Printf("Writing %d suggestions to %s", len(suggestions), folder)
+	err = os.WriteFile(filepath.Join(folder, "suggestions.md"), []byte(suggestionsMarkdown(suggestions)), os.FileMode(0644))
+	ohno(err)
+	err = os.WriteFile(filepath.Join(folder, "suggestions.opml"), suggestionsOpml(suggestions), os.FileMode(0644))
+	ohno(err)
+}
+
+func suggestionsMarkdown(suggestions []*Suggestion) string {
+	out := strings.Builder{}
+	out.WriteString("# Feeds you might like\n\n")
+	if len(suggestions) == 0 {
+		out.WriteString("No suggestions yet, blogrolls don't list any feeds you follow alongside others.\n")
+		return out.String()
+	}
+	out.WriteString("Feeds often listed alongside the feeds you follow, best first.\n\n")
+	for _, suggestion := range suggestions {
+		link := suggestion.HtmlUrl
+		if link == "" {
+			link = suggestion.FeedLink
+		}
+		fmt.Fprintf(&out, "- %s ([feed](<%s>)), score %.2f\n", markdownLink(suggestion.Title, link), suggestion.FeedLink, suggestion.Score)
+		fmt.Fprintf(&out, "  - Listed with %d feeds you follow in %d blogrolls\n", len(suggestion.CoListedWith), len(suggestion.Blogrolls))
+		if len(suggestion.SharedCategories) > 0 {
+			fmt.Fprintf(&out, "  - Shares categories: %s\n", strings.Join(suggestion.SharedCategories, ", "))
+		}
+	}
+	return out.String()
+}
+
+// See: https://opml.org/spec2.opml
+func suggestionsOpml(suggestions []*Suggestion) []byte {
+	root := xmlElement("opml", "", "version", "2.0")
+	head := xmlElement("head", "")
+	head.Inner = append(head.Inner, xmlElement("title", "Feeds you might like"))
+	body := xmlElement("body", "")
+	for _, suggestion := range suggestions {
+		text := suggestion.Title
+		if text == "" {
+			text = suggestion.FeedLink
+		}
+		attrs := []string{"type", "rss", "text", text, "xmlUrl", suggestion.FeedLink}
+		if suggestion.HtmlUrl != "" {
+			attrs = append(attrs, "htmlUrl", suggestion.HtmlUrl)
+		}
+		body.Inner = append(body.Inner, xmlElement("outline", "", attrs...))
+	}
+	root.Inner = append(root.Inner, head, body)
+	return marshalXml(root)
+}
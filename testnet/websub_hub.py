@@ -0,0 +1,61 @@
+#!/usr/bin/env python3
+"""
+A WebSub hub stand-in for local testing
+
+Accepts subscriptions, verifies the subscriber's intent and then pushes
+the current content of the topic to the subscriber's callback.
+
+    $ python3 websub_hub.py
+"""
+
+import threading
+import time
+import urllib.parse
+import urllib.request
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+PORT = 8001
+
+
+def verify_and_push(topic, callback):
+    time.sleep(1)
+    challenge = "challenge-%d" % time.time()
+    query = urllib.parse.urlencode({
+        "hub.mode": "subscribe",
+        "hub.topic": topic,
+        "hub.challenge": challenge,
+        "hub.lease_seconds": "60",
+    })
+    sep = "&" if "?" in callback else "?"
+    with urllib.request.urlopen(callback + sep + query) as resp:
+        if resp.read().decode() != challenge:
+            print("Verification failed:", topic)
+            return
+    print("Verified:", topic)
+
+    with urllib.request.urlopen(topic) as resp:
+        body = resp.read()
+    req = urllib.request.Request(callback, data=body, method="POST")
+    req.add_header("Content-Type", "application/rss+xml")
+    req.add_header("Link", '<http://localhost:%d/>; rel="hub", <%s>; rel="self"' % (PORT, topic))
+    with urllib.request.urlopen(req) as resp:
+        print("Pushed:", topic, resp.status)
+
+
+class Hub(BaseHTTPRequestHandler):
+    def do_POST(self):
+        length = int(self.headers.get("Content-Length", 0))
+        form = urllib.parse.parse_qs(self.rfile.read(length).decode())
+        topic = form.get("hub.topic", [""])[0]
+        callback = form.get("hub.callback", [""])[0]
+        if not topic or not callback:
+            self.send_response(400)
+            self.end_headers()
+            return
+        self.send_response(202)
+        self.end_headers()
+        threading.Thread(target=verify_and_push, args=(topic, callback)).start()
+
+
+if __name__ == "__main__":
+    HTTPServer(("localhost", PORT), Hub).serve_forever()
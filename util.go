@@ -8,6 +8,7 @@ import (
 	"github.com/go-yaml/yaml"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -34,6 +35,35 @@ func isWebLink(link string) bool {
 		strings.HasPrefix(lowLink, "gemini://")
 }
 
+// A key that's equal for URLs of the same page
+// Ignores the scheme, www. prefix, default ports and trailing slashes
+func siteKey(link string) string {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || parsed.Host == "" {
+		return link
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	port := parsed.Port()
+	if port != "" && port != "80" && port != "443" {
+		host = host + ":" + port
+	}
+	key := host + strings.TrimRight(parsed.EscapedPath(), "/")
+	if parsed.RawQuery != "" {
+		key = key + "?" + parsed.RawQuery
+	}
+	return key
+}
+
+// Links on the same host, ignoring the scheme and www. prefix
+func isSameSite(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	aHost, _, _ := strings.Cut(siteKey(a), "/")
+	bHost, _, _ := strings.Cut(siteKey(b), "/")
+	return aHost == bHost
+}
+
 func mkdirIfNotExists(path string) {
 	err := os.MkdirAll(path, 0755)
 	if err != nil {
@@ -104,29 +134,20 @@ func isBlockedDomain(url string, config *ParsedConfig) (bool, string) {
 
 func isBlockedPost(link, title, id string, config *ParsedConfig) bool {
 	if _, has := config.BlockPosts[title]; has {
-		log.Println("Blog blocked by title: %s", title)
+		log.Printf("Blog blocked by title: %s", title)
 		return true
 	}
 	if _, has := config.BlockPosts[link]; has {
-		log.Println("Blog blocked by link: %s", link)
+		log.Printf("Blog blocked by link: %s", link)
 		return true
 	}
 	if _, has := config.BlockPosts[id]; has {
-		log.Println("Blog blocked by ID: %s", id)
+		log.Printf("Blog blocked by ID: %s", id)
 		return true
 	}
 	return false
 }
 
-func hasBlockWords(text string, config *ParsedConfig) (bool, string) {
-	for _, blockedWord := range config.BlockWords {
-		if strings.Contains(text, blockedWord) {
-			return true, blockedWord
-		}
-	}
-	return false, ""
-}
-
 func buildSafeId(id, link string) string {
 	mustHash := false
 	if len(id) < 8 {
@@ -167,12 +188,14 @@ func cleanupContentOutputDirs(config *ParsedConfig) {
 	mkdirIfNotExists(config.DiscoverFolderName)
 	mkdirIfNotExists(config.NetworkFolderName)
 	mkdirIfNotExists(config.BlogrollFolderName)
+	mkdirIfNotExists(config.IdentityFolderName)
 	if config.RemoveOldContent {
 		rmGenerated(POST_PREFIX, config.ReadingFolderName)
 		rmGenerated(FEED_PREFIX, config.FollowingFolderName)
 		rmGenerated(FEED_PREFIX, config.DiscoverFolderName)
 		rmGenerated(LINK_PREFIX, config.NetworkFolderName)
 		rmGenerated(BLOGROLL_PREFIX, config.BlogrollFolderName)
+		rmGenerated(IDENTITY_PREFIX, config.IdentityFolderName)
 	}
 }
 
@@ -229,16 +252,56 @@ func languageFromLanguageTag(lang string) (string, error) {
 	}
 }
 
+// Keep the whole tag, with the conventional case for each subtag
+// For example: en-US, zh-Hant-TW, sr-Latn
+func normalizeLanguageTag(tag string) (string, error) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if _, err := languageFromLanguageTag(tag); err != nil {
+		return "", err
+	}
+	subtags := strings.Split(strings.ToLower(tag), "-")
+	for i, subtag := range subtags {
+		if i == 0 {
+			continue
+		}
+		if subtags[i-1] == "x" || (i > 1 && len(subtags[i-1]) == 1) {
+			// Private use and extensions are left alone
+			break
+		}
+		switch len(subtag) {
+		case 2:
+			// Region
+			subtags[i] = strings.ToUpper(subtag)
+		case 4:
+			// Script
+			subtags[i] = strings.ToUpper(subtag[:1]) + subtag[1:]
+		}
+	}
+	return strings.Join(subtags, "-"), nil
+}
+
+// Check a language tag against a list like ["en", "pt-BR"]
+func languageMatches(tag string, languages []string) bool {
+	tag = strings.ToLower(tag)
+	for _, language := range languages {
+		language = strings.ToLower(language)
+		if tag == language || strings.HasPrefix(tag, language+"-") {
+			return true
+		}
+	}
+	return false
+}
+
 func dedupeSlice[T comparable](sliceList []T) []T {
-    dedupeMap := make(map[T]struct{})
-    list := []T{}
+	dedupeMap := make(map[T]struct{})
+	list := []T{}
 
-    for _, slice := range sliceList {
-        if _, exists := dedupeMap[slice]; !exists {
-            dedupeMap[slice] = struct{}{}
-            list = append(list, slice)
-        }
-    }
+	for _, slice := range sliceList {
+		if _, exists := dedupeMap[slice]; !exists {
+			dedupeMap[slice] = struct{}{}
+			list = append(list, slice)
+		}
+	}
 
-    return list
+	return list
 }
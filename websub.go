@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/antchfx/xmlquery"
+	"github.com/gocolly/colly/v2"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A feed we'd like pushed updates for
+// See: https://www.w3.org/TR/websub/
+type WebSubTopic struct {
+	Topic string
+	Hub   string
+	Depth int
+}
+
+type WebSubSubscriber struct {
+	lock   sync.Mutex
+	topics map[string]WebSubTopic
+}
+
+func NewWebSubSubscriber() *WebSubSubscriber {
+	return &WebSubSubscriber{
+		topics: map[string]WebSubTopic{},
+	}
+}
+
+func (s *WebSubSubscriber) Track(topic WebSubTopic) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.topics[topic.Topic] = topic
+}
+
+func (s *WebSubSubscriber) Get(topic string) (WebSubTopic, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	found, ok := s.topics[topic]
+	return found, ok
+}
+
+func (s *WebSubSubscriber) Topics() []WebSubTopic {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	topics := []WebSubTopic{}
+	for _, topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// The URL that identifies a feed, prefer the feed's own rel=self link
+// Only a self link on the same site is trusted, so feeds can't claim others
+func (c *Crawler) canonicalFeedUrl(r *colly.Request, self string) string {
+	if isBackfillPage(r) {
+		return feedUrlOf(r)
+	}
+	requested := r.URL.String()
+	if self == "" || !isWebLink(self) {
+		return requested
+	}
+	_, self, ok := c.normalizeUrl(self)
+	if !ok {
+		return requested
+	}
+	if _, normalized, ok := c.normalizeUrl(requested); ok && self == normalized {
+		return requested
+	}
+	if !isSameSite(self, requested) {
+		log.Printf("Ignoring self link of %s on another site: %s", requested, self)
+		return requested
+	}
+	log.Printf("Feed %s identifies as: %s", requested, self)
+	r.Ctx.Put(CTX_SELF_URL, self)
+	return self
+}
+
+// Link the requested URL to the feed's self link, once the feed is kept
+func (c *Crawler) TrackSelfLink(r *colly.Request, feed_url string) {
+	if feed_url != r.URL.String() {
+		c.SaveLink(NewLinkFrontmatter(NODE_TYPE_FEED, r.URL.String(), NODE_TYPE_FEED, feed_url, LINK_TYPE_LINK_REL_SELF))
+	}
+}
+
+func (c *Crawler) findHubAndSelf(r *colly.Request, channel *xmlquery.Node) (string, string) {
+	hub := ""
+	hubs := collectLinkHrefsWithNamespace(r, c.HubLinkWithNamespaceXPath, channel)
+	if len(hubs) > 0 {
+		hub = hubs[0]
+	}
+	self := ""
+	selfs := collectLinkHrefsWithNamespace(r, c.SelfLinkWithNamespaceXPath, channel)
+	if len(selfs) > 0 {
+		self = selfs[0]
+	}
+	return hub, self
+}
+
+func (c *Crawler) TrackWebSub(f *FeedFrontmatter, depth int) {
+	if c.Config.WebSubCallbackUrl == "" || f.Params.Hub == "" {
+		return
+	}
+	c.WebSub.Track(WebSubTopic{
+		Topic: f.Params.FeedLink,
+		Hub:   f.Params.Hub,
+		Depth: depth,
+	})
+}
+
+func (c *Crawler) webSubCallback(topic string) string {
+	callback, err := url.Parse(c.Config.WebSubCallbackUrl)
+	ohno(err)
+	query := callback.Query()
+	query.Set("topic", topic)
+	callback.RawQuery = query.Encode()
+	return callback.String()
+}
+
+func (c *Crawler) webSubSubscribe(topic WebSubTopic) error {
+	form := url.Values{}
+	form.Set("hub.mode", "subscribe")
+	form.Set("hub.topic", topic.Topic)
+	form.Set("hub.callback", c.webSubCallback(topic.Topic))
+	form.Set("hub.lease_seconds", strconv.Itoa(int(c.Config.WebSubListenDuration/time.Second)))
+
+	req, err := http.NewRequest("POST", topic.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", USER_AGENT)
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 202 && resp.StatusCode != 204 {
+		return fmt.Errorf("Unexpected status code: %v", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *Crawler) OnWebSubCallback(w http.ResponseWriter, req *http.Request) {
+	topicUrl := req.URL.Query().Get("topic")
+	topic, ok := c.WebSub.Get(topicUrl)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		// Verification of intent
+		query := req.URL.Query()
+		mode := query.Get("hub.mode")
+		if mode == "denied" {
+			log.Printf("WebSub subscription denied: %s: %s", topic.Topic, query.Get("hub.reason"))
+			w.WriteHeader(200)
+			return
+		}
+		if query.Get("hub.topic") != topic.Topic {
+			http.NotFound(w, req)
+			return
+		}
+		log.Printf("WebSub %s verified: %s", mode, topic.Topic)
+		w.WriteHeader(200)
+		io.WriteString(w, query.Get("hub.challenge"))
+	case "POST":
+		// Content distribution
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		w.WriteHeader(200)
+		log.Printf("WebSub update for: %s", topic.Topic)
+		c.OnWebSubUpdate(topic, body)
+	default:
+		w.WriteHeader(405)
+	}
+}
+
+// Process pushed content the same way as a fetched feed
+func (c *Crawler) OnWebSubUpdate(topic WebSubTopic, body []byte) {
+	parsed, err := url.Parse(topic.Topic)
+	if err != nil {
+		return
+	}
+	ctx := colly.NewContext()
+	putNodeType(ctx, CTX_TARGET_TYPE, NODE_TYPE_FEED)
+	c.handleResponse(&colly.Response{
+		StatusCode: 200,
+		Body:       body,
+		Ctx:        ctx,
+		Request: &colly.Request{
+			URL:    parsed,
+			Method: "POST",
+			Depth:  topic.Depth,
+			Ctx:    ctx,
+		},
+		Headers: &http.Header{},
+	}, true)
+}
+
+// Subscribe to the hubs of followed feeds and process pushed updates
+// for the configured duration, then crawl anything the updates linked to
+func (c *Crawler) ListenWebSub() {
+	if c.Config.WebSubCallbackUrl == "" {
+		return
+	}
+	topics := c.WebSub.Topics()
+	if len(topics) == 0 {
+		log.Printf("No WebSub hubs found")
+		return
+	}
+
+	// The crawl queue can't be run twice, collect new requests in a fresh one
+	queue := newQueue(c.Config)
+	c.Queue = queue
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", c.OnWebSubCallback)
+	server := &http.Server{
+		Addr:    c.Config.WebSubListenAddr,
+		Handler: mux,
+	}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("WebSub listener error: %v", err)
+		}
+	}()
+
+	for _, topic := range topics {
+		err := c.webSubSubscribe(topic)
+		if err != nil {
+			log.Printf("WebSub subscribe error: %s: %v", topic.Topic, err)
+		}
+	}
+
+	log.Printf("Listening for WebSub updates on %s for %v", c.Config.WebSubListenAddr, c.Config.WebSubListenDuration)
+	time.Sleep(c.Config.WebSubListenDuration)
+	server.Shutdown(context.Background())
+
+	// Pushed updates may have queued more requests
+	err := queue.Run(c.Collector)
+	if err != nil {
+		log.Printf("Crawl error after WebSub: %v", err)
+	}
+}
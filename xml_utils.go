@@ -15,6 +15,14 @@ func xmlText(node *xmlquery.Node, xpathStr string) string {
 	return strings.TrimSpace(found.InnerText())
 }
 
+func xmlTextWithNamespace(node *xmlquery.Node, xpath *xpath.Expr) string {
+	found := xmlquery.QuerySelector(node, xpath)
+	if found == nil {
+		return ""
+	}
+	return strings.TrimSpace(found.InnerText())
+}
+
 func xmlTextMultiple(node *xmlquery.Node, xpathStr string) []string {
 	found := xmlquery.Find(node, xpathStr)
 	res := []string{}
@@ -70,3 +78,16 @@ func collectLinkHrefs(r *colly.Request, selectExpr string, node *xmlquery.Node)
 	}
 	return linkUrls
 }
+
+func collectLinkHrefsWithNamespace(r *colly.Request, expr *xpath.Expr, node *xmlquery.Node) []string {
+	links := xmlquery.QuerySelectorAll(node, expr)
+	linkUrls := []string{}
+	for _, link := range links {
+		url := xmlAttr(link, "href")
+		if url != "" {
+			url = r.AbsoluteURL(url)
+			linkUrls = append(linkUrls, url)
+		}
+	}
+	return linkUrls
+}